@@ -0,0 +1,113 @@
+// Package assets holds the images built into the bootstrapper, so they are
+// found wherever it is started from.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"golang.org/x/image/draw"
+)
+
+//go:embed Sylicity.png
+var logoPNG []byte
+
+// Logo is the Sylicity logo, used in the window and as the app icon.
+var Logo = fyne.NewStaticResource("Sylicity.png", logoPNG)
+
+// IconName is what the desktop entry calls the icon.
+const IconName = "sylicity"
+
+// IconSizes are the sizes InstallIcons writes.
+var IconSizes = []int{16, 22, 24, 32, 48, 64, 128, 256, 512}
+
+// IconPaths lists the icon files InstallIcons writes into the hicolor theme
+// at themeDir.
+func IconPaths(themeDir string) []string {
+	paths := make([]string, len(IconSizes))
+	for i, size := range IconSizes {
+		paths[i] = filepath.Join(themeDir, fmt.Sprintf("%dx%d", size, size), "apps", IconName+".png")
+	}
+	return paths
+}
+
+// IconStamp is the file InstallIcons records the logo's hash in, so it can
+// tell icons of an older logo from current ones.
+func IconStamp(themeDir string) string {
+	return filepath.Join(themeDir, "."+IconName+"-icons.sha256")
+}
+
+// InstallIcons writes the logo at every IconSizes size into the hicolor theme
+// at themeDir, e.g. ~/.local/share/icons/hicolor. Icons already there are
+// kept as long as they were made from the same logo.
+func InstallIcons(themeDir string) error {
+	return installIcons(themeDir, logoPNG)
+}
+
+func installIcons(themeDir string, logo []byte) error {
+	sum := sha256.Sum256(logo)
+	hash := hex.EncodeToString(sum[:])
+	if upToDate(themeDir, hash) {
+		return nil
+	}
+
+	src, err := png.Decode(bytes.NewReader(logo))
+	if err != nil {
+		return fmt.Errorf("failed to decode logo: %w", err)
+	}
+	for i, path := range IconPaths(themeDir) {
+		size := IconSizes[i]
+		icon := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(icon, icon.Bounds(), src, src.Bounds(), draw.Src, nil)
+		if err := writePNG(path, icon); err != nil {
+			return fmt.Errorf("failed to write %dpx icon: %w", size, err)
+		}
+	}
+
+	if err := os.WriteFile(IconStamp(themeDir), []byte(hash+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to record icon hash: %w", err)
+	}
+
+	// Desktops rescan the theme when its directory changes.
+	now := time.Now()
+	os.Chtimes(themeDir, now, now)
+	return nil
+}
+
+func upToDate(themeDir, hash string) bool {
+	stamp, err := os.ReadFile(IconStamp(themeDir))
+	if err != nil || strings.TrimSpace(string(stamp)) != hash {
+		return false
+	}
+	for _, path := range IconPaths(themeDir) {
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
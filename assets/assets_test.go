@@ -0,0 +1,123 @@
+package assets
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+)
+
+func solidPNG(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for x := range 8 {
+		for y := range 8 {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func iconColor(t *testing.T, path string) color.NRGBA {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA)
+}
+
+func TestInstallIconsReplacesOldLogo(t *testing.T) {
+	dir := t.TempDir()
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	green := color.NRGBA{G: 0xff, A: 0xff}
+	paths := IconPaths(dir)
+
+	if err := installIcons(dir, solidPNG(t, red)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if got := iconColor(t, path); got != red {
+			t.Fatalf("%s is %v, want %v", path, got, red)
+		}
+	}
+
+	// The same logo again leaves the icons alone.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(paths[0], old, old)
+	if err := installIcons(dir, solidPNG(t, red)); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(paths[0]); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("icon rewritten for an unchanged logo")
+	}
+
+	if err := installIcons(dir, solidPNG(t, green)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if got := iconColor(t, path); got != green {
+			t.Errorf("%s still has the old logo: %v", path, got)
+		}
+	}
+}
+
+func TestLogo(t *testing.T) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(logoPNG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if largest := IconSizes[len(IconSizes)-1]; cfg.Width != cfg.Height || cfg.Width < largest {
+		t.Errorf("logo is %dx%d, want a square of at least %dpx", cfg.Width, cfg.Height, largest)
+	}
+}
+
+func TestInstallIcons(t *testing.T) {
+	dir := t.TempDir()
+	logo := solidPNG(t, color.NRGBA{R: 0xff, A: 0xff})
+	if err := installIcons(dir, logo); err != nil {
+		t.Fatal(err)
+	}
+	paths := IconPaths(dir)
+	for i, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := png.DecodeConfig(f)
+		f.Close()
+		if size := IconSizes[i]; err != nil || cfg.Width != size || cfg.Height != size {
+			t.Errorf("%s is %dx%d, %v; want %dpx", path, cfg.Width, cfg.Height, err, size)
+		}
+	}
+
+	// The same logo again leaves the icons alone.
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(paths[0], old, old)
+	if err := installIcons(dir, logo); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(paths[0]); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("icon rewritten for an unchanged logo")
+	}
+
+	os.Remove(paths[3])
+	if err := installIcons(dir, logo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(paths[3]); err != nil {
+		t.Errorf("missing icon not restored: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/progress"
+	"sylicitybootstrapper/selfupdate"
+	"sylicitybootstrapper/uninstall"
+)
+
+func runCLI(cfg *config.Config, opts LaunchOptions) int {
+	switch opts.LaunchMode {
+	case "uninstall":
+		return runUninstallCLI(cfg, opts)
+	case "install", "play":
+		return runInstallCLI(cfg, opts)
+	case "verify", "repair":
+		return runVerifyCLI(cfg, opts)
+	case "diagnostics":
+		return runDiagnosticsCLI(cfg, opts)
+	default:
+		fmt.Println(l10n.T("cli.unsupportedMode", "Mode", opts.LaunchMode))
+		return 2
+	}
+}
+
+// cliProgress prints a line whenever the stage or client changes, the
+// overall progress moves by five percent, or at least once a second.
+func cliProgress(opts LaunchOptions) func(progress.Snapshot) {
+	if opts.JSONOutput {
+		var mu sync.Mutex
+		encoder := json.NewEncoder(os.Stdout)
+		return func(snapshot progress.Snapshot) {
+			mu.Lock()
+			defer mu.Unlock()
+			encoder.Encode(snapshot)
+		}
+	}
+
+	var mu sync.Mutex
+	lastKey := ""
+	lastPercent := -1
+	var lastPrint time.Time
+	return func(snapshot progress.Snapshot) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		key := snapshot.Stage.String() + "/" + snapshot.Item
+		percent := int(snapshot.Overall * 100)
+		switch {
+		case key != lastKey:
+		case percent/5 != lastPercent/5:
+		case time.Since(lastPrint) >= time.Second:
+		default:
+			return
+		}
+		lastKey, lastPercent, lastPrint = key, percent, time.Now()
+		fmt.Printf("[%3d%%] %s\n", percent, snapshot.Message)
+	}
+}
+
+// cliResult prints the outcome of a headless run and returns its exit code.
+func cliResult(opts LaunchOptions, err error, message string) int {
+	if opts.JSONOutput {
+		result := map[string]string{"result": "ok", "message": message}
+		if err != nil {
+			result = map[string]string{"result": "error", "error": err.Error()}
+		}
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else if err != nil {
+		fmt.Println(l10n.T("common.error", "Err", err))
+	} else if message != "" {
+		fmt.Println(message)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runInstallCLI(cfg *config.Config, opts LaunchOptions) int {
+	if os.Getenv(selfupdate.RelaunchedEnv) == "" {
+		if err := checkForSelfUpdate(cfg, func(msg string) { fmt.Println(msg) }); err != nil {
+			slog.Warn("self-update failed", "err", err)
+		}
+	}
+
+	forceInstall := opts.LaunchMode != "play"
+	tracker := progress.NewTracker(progress.InstallStages, cliProgress(opts))
+
+	if err := installClients(cfg, opts.Channel, opts.ClientYear, tracker, forceInstall); err != nil {
+		return cliResult(opts, fmt.Errorf("failed to install clients: %w", err), "")
+	}
+
+	registerSylicity(tracker)
+
+	if opts.LaunchMode == "play" {
+		exit, err := launchClient(cfg, opts, func() {
+			if opts.JSONOutput {
+				tracker.Message(progress.StageRegister, "", install.WaitingMessage())
+			} else {
+				fmt.Println(install.WaitingMessage())
+			}
+		})
+		if err != nil {
+			return cliResult(opts, fmt.Errorf("%s: %w", l10n.T("installer.launchFailed"), err), "")
+		}
+		if exit != nil {
+			if !opts.JSONOutput && exit.Excerpt != "" {
+				fmt.Println(exit.Excerpt)
+			}
+			return cliResult(opts, errors.New(l10n.T("cli.crashed", "Code", exit.Code)), "")
+		}
+		return cliResult(opts, nil, l10n.T("cli.started"))
+	}
+
+	return cliResult(opts, nil, l10n.T("installer.ready"))
+}
+
+func runVerifyCLI(cfg *config.Config, opts LaunchOptions) int {
+	repair := opts.LaunchMode == "repair"
+	stages := progress.VerifyStages
+	if repair {
+		stages = progress.ClientStages
+	}
+	tracker := progress.NewTracker(stages, cliProgress(opts))
+	results, err := verifyInstalledClients(cfg, opts.Channel, opts.ClientYear, repair, tracker)
+
+	if opts.JSONOutput {
+		json.NewEncoder(os.Stdout).Encode(map[string]any{"results": results})
+		for _, result := range results {
+			if !result.OK() && err == nil {
+				err = fmt.Errorf("client %s has broken files", result.Year)
+			}
+		}
+		return cliResult(opts, err, "")
+	}
+
+	status := 0
+	for _, result := range results {
+		fmt.Println(result.Summary())
+		for _, name := range result.Missing {
+			fmt.Println("  " + l10n.T("verify.missingFile", "Path", name))
+		}
+		for _, name := range result.Modified {
+			fmt.Println("  " + l10n.T("verify.modifiedFile", "Path", name))
+		}
+		for _, name := range result.Extra {
+			fmt.Println("  " + l10n.T("verify.extraFile", "Path", name))
+		}
+		if !result.OK() {
+			status = 1
+		}
+	}
+	if err != nil {
+		fmt.Println(l10n.T("common.error", "Err", err))
+		return 1
+	}
+	if len(results) == 0 {
+		fmt.Println(l10n.T("verify.nothingInstalled"))
+	}
+	if status != 0 && !repair {
+		fmt.Println(l10n.T("cli.repairHint"))
+	}
+	return status
+}
+
+func runUninstallCLI(cfg *config.Config, opts LaunchOptions) int {
+	plan, err := newUninstallPlan(cfg)
+	if err != nil {
+		fmt.Println(l10n.T("common.error", "Err", err))
+		return 1
+	}
+
+	if opts.ClientYear != "" {
+		for _, client := range uninstall.FindClients(getVersionsDirs(cfg)) {
+			if client.Year == opts.ClientYear && client.Channel == opts.Channel {
+				plan.Clients = append(plan.Clients, client)
+			}
+		}
+		if len(plan.Clients) == 0 {
+			fmt.Println(l10n.T("cli.notInstalled", "Client", l10n.T("client.nameWithChannel", "Year", opts.ClientYear, "Channel", opts.Channel)))
+			return 1
+		}
+	} else {
+		plan.RemoveAll = true
+		switch {
+		case opts.KeepData:
+			plan.KeepData = true
+		case opts.PurgeData:
+			plan.KeepData = false
+		case opts.JSONOutput:
+			plan.KeepData = true
+		default:
+			plan.KeepData = !askYesNo(l10n.T("cli.deleteData", "Dir", plan.AppDir))
+		}
+	}
+
+	report := uninstall.Run(plan)
+	slog.Info("uninstall finished", "removed", report.Removed, "err", report.Err())
+	if opts.JSONOutput {
+		json.NewEncoder(os.Stdout).Encode(map[string]any{"removed": report.Removed})
+		return cliResult(opts, report.Err(), "")
+	}
+	printUninstallReport(report)
+	if report.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+func runDiagnosticsCLI(cfg *config.Config, opts LaunchOptions) int {
+	path := opts.OutputPath
+	if path == "" {
+		path = diagnosticsFileName()
+	}
+	err := exportDiagnostics(cfg, opts, path)
+	return cliResult(opts, err, l10n.T("cli.diagnosticsSaved", "Path", path))
+}
+
+func printUninstallReport(report *uninstall.Report) {
+	if len(report.Removed) == 0 {
+		fmt.Println(l10n.T("uninstall.nothingRemoved"))
+	}
+	for _, item := range report.Removed {
+		fmt.Println(l10n.T("uninstall.removed", "Item", item))
+	}
+	for _, err := range report.Errors {
+		fmt.Println(l10n.T("common.error", "Err", err))
+	}
+}
+
+func askYesNo(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
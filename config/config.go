@@ -0,0 +1,454 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	FileName       = "config.json"
+	SchemaFileName = "config.schema.json"
+	envPrefix      = "SYLICITY_"
+
+	StableChannel = "stable"
+)
+
+//go:embed config.schema.json
+var schema []byte
+
+type LaunchOverrides struct {
+	Wrapper   []string          `json:"wrapper,omitempty"`
+	ExtraArgs []string          `json:"extraArgs,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	// Supervise keeps watching the client after launch and reports it if it
+	// crashes on start-up.
+	Supervise bool `json:"supervise,omitempty"`
+}
+
+type ThemeSettings struct {
+	// SystemAccent takes the primary color from the desktop's accent color.
+	SystemAccent bool `json:"systemAccent,omitempty"`
+}
+
+type Config struct {
+	Schema              string            `json:"$schema,omitempty"`
+	VersionURL          string            `json:"versionUrl"`
+	DownloadURLBase     string            `json:"downloadUrlBase"`
+	ClientVersionsAPI   string            `json:"clientVersionsApi"`
+	AuthURL             string            `json:"authUrl"`
+	InstallDir          string            `json:"installDir"`
+	DefaultClientYear   string            `json:"defaultClientYear"`
+	DownloadConcurrency int               `json:"downloadConcurrency"`
+	BandwidthLimit      string            `json:"bandwidthLimit"`
+	BandwidthSchedule   []string          `json:"bandwidthSchedule,omitempty"`
+	Channel             string            `json:"channel"`
+	Channels            map[string]string `json:"channels"`
+	LogLevel            string            `json:"logLevel"`
+	Language            string            `json:"language,omitempty"`
+	Launch              LaunchOverrides   `json:"launch"`
+	Theme               ThemeSettings     `json:"theme"`
+}
+
+func Default(appDir string) *Config {
+	return &Config{
+		Schema:              "./" + SchemaFileName,
+		VersionURL:          "https://setup.no.lol/version",                     // currently placeholder
+		DownloadURLBase:     "https://setup.no.lol/",                            // currently placeholder
+		ClientVersionsAPI:   "https://clientversions.no.lol/v1/client-versions", // currently placeholder
+		AuthURL:             "https://www.kroner.lol/Login/Negotiate.ashx",
+		InstallDir:          appDir,
+		DefaultClientYear:   "2016",
+		DownloadConcurrency: 2,
+		BandwidthLimit:      "",
+		Channel:             StableChannel,
+		LogLevel:            "info",
+		Channels: map[string]string{
+			"beta": "https://clientversions.no.lol/v1/client-versions/beta", // currently placeholder
+			"dev":  "https://clientversions.no.lol/v1/client-versions/dev",  // currently placeholder
+		},
+	}
+}
+
+// Load reads path on top of the defaults. A missing file is written out with
+// the defaults so users have something to edit; failing to write it is only
+// logged, since the defaults still work.
+func Load(path, appDir string) (*Config, error) {
+	cfg := Default(appDir)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := Save(path, cfg); err != nil {
+			slog.Warn("could not write default config", "path", path, "err", err)
+		}
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func Save(path string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), SchemaFileName), schema, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+type field struct {
+	key string
+	set func(c *Config, value string) error
+}
+
+func stringField(key string, ptr func(*Config) *string) field {
+	return field{key, func(c *Config, value string) error {
+		*ptr(c) = value
+		return nil
+	}}
+}
+
+func listField(key string, ptr func(*Config) *[]string) field {
+	return field{key, func(c *Config, value string) error {
+		*ptr(c) = strings.Fields(value)
+		return nil
+	}}
+}
+
+func boolField(key string, ptr func(*Config) *bool) field {
+	return field{key, func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*ptr(c) = b
+		return nil
+	}}
+}
+
+var fields = []field{
+	stringField("versionUrl", func(c *Config) *string { return &c.VersionURL }),
+	stringField("downloadUrlBase", func(c *Config) *string { return &c.DownloadURLBase }),
+	stringField("clientVersionsApi", func(c *Config) *string { return &c.ClientVersionsAPI }),
+	stringField("authUrl", func(c *Config) *string { return &c.AuthURL }),
+	stringField("installDir", func(c *Config) *string { return &c.InstallDir }),
+	stringField("defaultClientYear", func(c *Config) *string { return &c.DefaultClientYear }),
+	{"downloadConcurrency", func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", value)
+		}
+		c.DownloadConcurrency = n
+		return nil
+	}},
+	stringField("bandwidthLimit", func(c *Config) *string { return &c.BandwidthLimit }),
+	listField("bandwidthSchedule", func(c *Config) *[]string { return &c.BandwidthSchedule }),
+	stringField("channel", func(c *Config) *string { return &c.Channel }),
+	stringField("logLevel", func(c *Config) *string { return &c.LogLevel }),
+	stringField("language", func(c *Config) *string { return &c.Language }),
+	listField("launch.wrapper", func(c *Config) *[]string { return &c.Launch.Wrapper }),
+	listField("launch.extraArgs", func(c *Config) *[]string { return &c.Launch.ExtraArgs }),
+	boolField("launch.supervise", func(c *Config) *bool { return &c.Launch.Supervise }),
+	boolField("theme.systemAccent", func(c *Config) *bool { return &c.Theme.SystemAccent }),
+}
+
+// Keys lists the settings accepted by Set, e.g. for the -set flag. The
+// channels and launch.env maps are only read from the config file.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// EnvName maps a key such as "downloadConcurrency" to SYLICITY_DOWNLOAD_CONCURRENCY.
+func EnvName(key string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for i, r := range key {
+		switch {
+		case r == '.':
+			b.WriteRune('_')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && key[i-1] != '.' {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteString(strings.ToUpper(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func (c *Config) Set(key, value string) error {
+	if name, ok := strings.CutPrefix(key, "channels."); ok {
+		if c.Channels == nil {
+			c.Channels = map[string]string{}
+		}
+		c.Channels[name] = value
+		return nil
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.key, key) {
+			if err := f.set(c, value); err != nil {
+				return fmt.Errorf("%s: %w", f.key, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown setting %q (known settings: %s)", key, strings.Join(Keys(), ", "))
+}
+
+// ApplyEnv overrides settings from SYLICITY_* environment variables.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	for _, f := range fields {
+		env := EnvName(f.key)
+		if value := getenv(env); value != "" {
+			if err := f.set(c, value); err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyOverrides applies command line overrides, which take precedence over
+// both the config file and the environment.
+func (c *Config) ApplyOverrides(overrides map[string]string) error {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := c.Set(key, overrides[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	channelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	yearPattern    = regexp.MustCompile(`^[0-9]{4}$`)
+)
+
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	check("versionUrl", validateURL(c.VersionURL))
+	check("downloadUrlBase", validateURL(c.DownloadURLBase))
+	check("clientVersionsApi", validateURL(c.ClientVersionsAPI))
+	check("authUrl", validateURL(c.AuthURL))
+
+	if c.InstallDir == "" || !filepath.IsAbs(c.InstallDir) {
+		check("installDir", fmt.Errorf("must be an absolute path, got %q", c.InstallDir))
+	}
+	if !yearPattern.MatchString(c.DefaultClientYear) {
+		check("defaultClientYear", fmt.Errorf("must be a four digit year, got %q", c.DefaultClientYear))
+	}
+	if c.DownloadConcurrency < 1 || c.DownloadConcurrency > 8 {
+		check("downloadConcurrency", fmt.Errorf("must be between 1 and 8, got %d", c.DownloadConcurrency))
+	}
+	_, err := ParseByteRate(c.BandwidthLimit)
+	check("bandwidthLimit", err)
+	for _, entry := range c.BandwidthSchedule {
+		_, err := ParseBandwidthWindow(entry)
+		check("bandwidthSchedule", err)
+	}
+	for name, manifestURL := range c.Channels {
+		if !channelPattern.MatchString(name) {
+			check("channels", fmt.Errorf("channel names must be lowercase letters, digits and dashes, got %q", name))
+		}
+		check("channels."+name, validateURL(manifestURL))
+	}
+	if _, err := c.ManifestURL(c.Channel); err != nil {
+		check("channel", err)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		check("logLevel", fmt.Errorf("must be debug, info, warn or error, got %q", c.LogLevel))
+	}
+	if c.Language != "" {
+		if _, err := language.Parse(c.Language); err != nil {
+			check("language", fmt.Errorf("must be a language tag such as \"de\" or \"pt-BR\", got %q", c.Language))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ChannelNames lists the stable channel followed by every configured channel.
+func (c *Config) ChannelNames() []string {
+	names := []string{StableChannel}
+	for name := range c.Channels {
+		if name != StableChannel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// ManifestURL resolves the client versions manifest for channel. The stable
+// channel uses clientVersionsApi unless it is listed in channels explicitly.
+func (c *Config) ManifestURL(channel string) (string, error) {
+	if manifestURL, ok := c.Channels[channel]; ok {
+		return manifestURL, nil
+	}
+	if channel == StableChannel {
+		return c.ClientVersionsAPI, nil
+	}
+	return "", fmt.Errorf("unknown channel %q (known channels: %s)", channel, strings.Join(c.ChannelNames(), ", "))
+}
+
+// VersionsDir is where clients of channel are installed. Stable keeps the
+// original Versions folder so existing installs stay valid; every other
+// channel gets its own subdirectory.
+func (c *Config) VersionsDir(channel string) string {
+	versionsDir := filepath.Join(c.InstallDir, "Versions")
+	if channel == "" || channel == StableChannel {
+		return versionsDir
+	}
+	return filepath.Join(versionsDir, channel)
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http(s) URL, got %q", raw)
+	}
+	return nil
+}
+
+var byteRatePattern = regexp.MustCompile(`^(?i)\s*([0-9]+(?:\.[0-9]+)?)\s*(b|kb|mb|gb|kib|mib|gib)?(?:/s)?\s*$`)
+
+// ParseByteRate parses limits such as "500KB/s" or "2.5MB/s" into bytes per
+// second. An empty string or "0" means unlimited and returns 0.
+func ParseByteRate(value string) (int64, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	m := byteRatePattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("must look like 500KB/s or 2MB/s, got %q", value)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+	multiplier := map[string]float64{
+		"": 1, "b": 1,
+		"kb": 1000, "mb": 1000 * 1000, "gb": 1000 * 1000 * 1000,
+		"kib": 1024, "mib": 1024 * 1024, "gib": 1024 * 1024 * 1024,
+	}[strings.ToLower(m[2])]
+	return int64(n * multiplier), nil
+}
+
+// BandwidthWindow limits downloads to Rate bytes per second from Start until
+// End, both in minutes after midnight. A window whose End is before its Start
+// runs past midnight, and one whose End equals its Start, such as
+// 00:00-24:00 or 00:00-00:00, lasts all day.
+type BandwidthWindow struct {
+	Start, End int
+	Rate       int64
+}
+
+var bandwidthWindowPattern = regexp.MustCompile(`^([0-9]{1,2}):([0-9]{2})-([0-9]{1,2}):([0-9]{2})=(.+)$`)
+
+// ParseBandwidthWindow parses entries such as "08:00-18:00=500KB/s" or
+// "22:00-06:00=0", where 0 lifts the limit.
+func ParseBandwidthWindow(value string) (BandwidthWindow, error) {
+	m := bandwidthWindowPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return BandwidthWindow{}, fmt.Errorf("must look like 08:00-18:00=500KB/s, got %q", value)
+	}
+	var minutes [2]int
+	for i := range minutes {
+		hour, _ := strconv.Atoi(m[1+2*i])
+		minute, _ := strconv.Atoi(m[2+2*i])
+		if hour > 24 || minute > 59 || (hour == 24 && minute != 0) {
+			return BandwidthWindow{}, fmt.Errorf("%q is not a time of day", m[1+2*i]+":"+m[2+2*i])
+		}
+		minutes[i] = hour*60 + minute
+	}
+	rate, err := ParseByteRate(m[5])
+	if err != nil {
+		return BandwidthWindow{}, err
+	}
+	return BandwidthWindow{Start: minutes[0], End: minutes[1], Rate: rate}, nil
+}
+
+// Contains reports whether now falls into the window, in local time.
+func (w BandwidthWindow) Contains(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	if w.Start%(24*60) == w.End%(24*60) {
+		return true
+	}
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// DownloadRate returns the download limit in bytes per second at a given
+// time: the first bandwidthSchedule window that contains it, otherwise
+// bandwidthLimit. 0 means unlimited.
+func (c *Config) DownloadRate() (func(now time.Time) int64, error) {
+	limit, err := ParseByteRate(c.BandwidthLimit)
+	if err != nil {
+		return nil, fmt.Errorf("bandwidthLimit: %w", err)
+	}
+	windows := make([]BandwidthWindow, 0, len(c.BandwidthSchedule))
+	for _, entry := range c.BandwidthSchedule {
+		window, err := ParseBandwidthWindow(entry)
+		if err != nil {
+			return nil, fmt.Errorf("bandwidthSchedule: %w", err)
+		}
+		windows = append(windows, window)
+	}
+	return func(now time.Time) int64 {
+		for _, window := range windows {
+			if window.Contains(now) {
+				return window.Rate
+			}
+		}
+		return limit
+	}, nil
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadWritesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+
+	cfg, err := Load(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InstallDir != dir {
+		t.Errorf("installDir = %q, want %q", cfg.InstallDir, dir)
+	}
+	for _, name := range []string{FileName, SchemaFileName} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+}
+
+func TestLoadWithoutWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	// A directory where the schema should go makes the write fail.
+	if err := os.Mkdir(filepath.Join(dir, SchemaFileName), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(filepath.Join(dir, FileName), dir)
+	if err != nil {
+		t.Fatalf("Load = %v, want the defaults without an error", err)
+	}
+	if cfg.DefaultClientYear != Default(dir).DefaultClientYear {
+		t.Errorf("config is not the defaults: %+v", cfg)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		file, env string
+		flag      string
+		wantYear  string
+	}{
+		{name: "default", wantYear: "2016"},
+		{name: "file", file: "2018", wantYear: "2018"},
+		{name: "env over file", file: "2018", env: "2020", wantYear: "2020"},
+		{name: "flag over env", file: "2018", env: "2020", flag: "2021", wantYear: "2021"},
+		{name: "flag over file", file: "2018", flag: "2021", wantYear: "2021"},
+		{name: "env over default", env: "2020", wantYear: "2020"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, FileName)
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(`{"defaultClientYear": "`+tt.file+`"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := Load(path, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env := map[string]string{"SYLICITY_DEFAULT_CLIENT_YEAR": tt.env}
+			if err := cfg.ApplyEnv(func(key string) string { return env[key] }); err != nil {
+				t.Fatal(err)
+			}
+			overrides := map[string]string{}
+			if tt.flag != "" {
+				overrides["defaultClientYear"] = tt.flag
+			}
+			if err := cfg.ApplyOverrides(overrides); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DefaultClientYear != tt.wantYear {
+				t.Errorf("defaultClientYear = %q, want %q", cfg.DefaultClientYear, tt.wantYear)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte(`{"downloadConcurency": 4}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, dir); err == nil || !strings.Contains(err.Error(), `unknown field "downloadConcurency"`) {
+		t.Errorf("Load = %v, want an unknown field error", err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"downloadConcurrency": "SYLICITY_DOWNLOAD_CONCURRENCY",
+		"launch.extraArgs":    "SYLICITY_LAUNCH_EXTRA_ARGS",
+		"theme.systemAccent":  "SYLICITY_THEME_SYSTEM_ACCENT",
+		"channel":             "SYLICITY_CHANNEL",
+	}
+	for key, want := range tests {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{
+			name: "one problem",
+			modify: func(c *Config) {
+				c.DownloadConcurrency = 9
+			},
+			want: []string{"downloadConcurrency: must be between 1 and 8, got 9"},
+		},
+		{
+			name: "every problem",
+			modify: func(c *Config) {
+				c.VersionURL = "ftp://example.com"
+				c.InstallDir = "relative"
+				c.DefaultClientYear = "16"
+				c.DownloadConcurrency = 0
+				c.BandwidthLimit = "fast"
+				c.BandwidthSchedule = []string{"8-18=1MB/s"}
+				c.Channels = map[string]string{"Beta": "https://example.com"}
+				c.Channel = "nightly"
+				c.LogLevel = "loud"
+				c.Language = "not a language"
+			},
+			want: []string{
+				`versionUrl: must be an http(s) URL, got "ftp://example.com"`,
+				`installDir: must be an absolute path, got "relative"`,
+				`defaultClientYear: must be a four digit year, got "16"`,
+				"downloadConcurrency: must be between 1 and 8, got 0",
+				`bandwidthLimit: must look like 500KB/s or 2MB/s, got "fast"`,
+				`bandwidthSchedule: must look like 08:00-18:00=500KB/s, got "8-18=1MB/s"`,
+				`channels: channel names must be lowercase letters, digits and dashes, got "Beta"`,
+				`channel: unknown channel "nightly"`,
+				`logLevel: must be debug, info, warn or error, got "loud"`,
+				`language: must be a language tag`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default(t.TempDir())
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Validate = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate = nil, want an error")
+			}
+			if lines := strings.Split(err.Error(), "\n"); len(lines) != len(tt.want) {
+				t.Errorf("got %d problems, want %d:\n%v", len(lines), len(tt.want), err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error does not mention %q:\n%v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseByteRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "", want: 0},
+		{in: "0", want: 0},
+		{in: "1500", want: 1500},
+		{in: "500B/s", want: 500},
+		{in: "500KB/s", want: 500_000},
+		{in: "500kb", want: 500_000},
+		{in: "2.5MB/s", want: 2_500_000},
+		{in: "1GB/s", want: 1_000_000_000},
+		{in: "1KiB/s", want: 1024},
+		{in: "1.5MiB/s", want: 1536 * 1024},
+		{in: " 2 MB/s ", want: 2_000_000},
+		{in: "fast", wantErr: true},
+		{in: "-1MB/s", wantErr: true},
+		{in: "1TB/s", wantErr: true},
+		{in: "1MB/h", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteRate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseByteRate(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseBandwidthWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BandwidthWindow
+		wantErr bool
+	}{
+		{in: "08:00-18:00=500KB/s", want: BandwidthWindow{Start: 8 * 60, End: 18 * 60, Rate: 500_000}},
+		{in: "22:00-06:00=0", want: BandwidthWindow{Start: 22 * 60, End: 6 * 60}},
+		{in: "8:30-24:00=1MB/s", want: BandwidthWindow{Start: 8*60 + 30, End: 24 * 60, Rate: 1_000_000}},
+		{in: " 00:00-00:00=1KiB/s ", want: BandwidthWindow{Rate: 1024}},
+		{in: "08:00-18:00", wantErr: true},
+		{in: "8-18=1MB/s", wantErr: true},
+		{in: "08:00-18:60=1MB/s", wantErr: true},
+		{in: "25:00-06:00=1MB/s", wantErr: true},
+		{in: "24:30-06:00=1MB/s", wantErr: true},
+		{in: "08:00-18:00=fast", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBandwidthWindow(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBandwidthWindow(%q) = %+v, %v; want %+v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func at(hour, minute int) time.Time {
+	return time.Date(2025, 1, 1, hour, minute, 0, 0, time.Local)
+}
+
+func TestBandwidthWindowContains(t *testing.T) {
+	tests := []struct {
+		window string
+		in     []time.Time
+		out    []time.Time
+	}{
+		{"08:00-18:00=1", []time.Time{at(8, 0), at(12, 0), at(17, 59)}, []time.Time{at(7, 59), at(18, 0), at(0, 0)}},
+		{"22:00-06:00=1", []time.Time{at(22, 0), at(23, 59), at(0, 0), at(5, 59)}, []time.Time{at(6, 0), at(12, 0), at(21, 59)}},
+		{"18:00-24:00=1", []time.Time{at(18, 0), at(23, 59)}, []time.Time{at(0, 0), at(17, 59)}},
+		{"00:00-24:00=1", []time.Time{at(0, 0), at(12, 0), at(23, 59)}, nil},
+		{"00:00-00:00=1", []time.Time{at(0, 0), at(12, 0), at(23, 59)}, nil},
+		{"09:30-09:30=1", []time.Time{at(9, 29), at(9, 30), at(21, 0)}, nil},
+	}
+	for _, tt := range tests {
+		window, err := ParseBandwidthWindow(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, now := range tt.in {
+			if !window.Contains(now) {
+				t.Errorf("%s does not contain %s", tt.window, now.Format("15:04"))
+			}
+		}
+		for _, now := range tt.out {
+			if window.Contains(now) {
+				t.Errorf("%s contains %s", tt.window, now.Format("15:04"))
+			}
+		}
+	}
+}
+
+func TestDownloadRate(t *testing.T) {
+	cfg := Default(t.TempDir())
+	cfg.BandwidthLimit = "5MB/s"
+	cfg.BandwidthSchedule = []string{"08:00-18:00=500KB/s", "12:00-13:00=0", "22:00-06:00=0"}
+	rate, err := cfg.DownloadRate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		now  time.Time
+		want int64
+	}{
+		{at(9, 0), 500_000},
+		// The first matching entry wins over the lunch break listed after it.
+		{at(12, 30), 500_000},
+		{at(23, 0), 0},
+		{at(3, 0), 0},
+		{at(19, 0), 5_000_000},
+		{at(6, 0), 5_000_000},
+	}
+	for _, tt := range tests {
+		if got := rate(tt.now); got != tt.want {
+			t.Errorf("rate at %s = %d, want %d", tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+
+	cfg.BandwidthLimit = ""
+	cfg.BandwidthSchedule = nil
+	if rate, err := cfg.DownloadRate(); err != nil || rate(at(12, 0)) != 0 {
+		t.Errorf("without limits, DownloadRate = %v; want unlimited", err)
+	}
+
+	for _, bad := range []func(c *Config){
+		func(c *Config) { c.BandwidthLimit = "fast" },
+		func(c *Config) { c.BandwidthSchedule = []string{"08:00-18:00=1MB/s", "soon"} },
+	} {
+		cfg := Default(t.TempDir())
+		bad(cfg)
+		if rate, err := cfg.DownloadRate(); err == nil || rate != nil {
+			t.Errorf("DownloadRate with a malformed entry = %v; want an error", err)
+		}
+	}
+}
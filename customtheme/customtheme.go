@@ -0,0 +1,298 @@
+// Package customtheme loads theme.json from the app directory, which lets a
+// community build change the bootstrapper's colors, fonts, sizes and logo
+// without recompiling.
+package customtheme
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+const FileName = "theme.json"
+
+// File is theme.json as written by the user. Paths are relative to the
+// directory the file is in.
+type File struct {
+	// Colors maps "dark" and "light" to color overrides such as
+	// {"primary": "#27b53d"}.
+	Colors map[string]map[string]string `json:"colors,omitempty"`
+	// Fonts maps regular, bold, italic, boldItalic and monospace to font files.
+	Fonts map[string]string  `json:"fonts,omitempty"`
+	Sizes map[string]float32 `json:"sizes,omitempty"`
+	Logo  string             `json:"logo,omitempty"`
+}
+
+// Theme is a validated theme file, ready to be applied.
+type Theme struct {
+	Colors map[fyne.ThemeVariant]map[fyne.ThemeColorName]color.Color
+	Fonts  map[fyne.TextStyle]fyne.Resource
+	Sizes  map[fyne.ThemeSizeName]float32
+	// Logo is nil when the file keeps the built-in logo.
+	Logo fyne.Resource
+}
+
+var variants = map[string]fyne.ThemeVariant{
+	"dark":  theme.VariantDark,
+	"light": theme.VariantLight,
+}
+
+var fontStyles = map[string]fyne.TextStyle{
+	"regular":    {},
+	"bold":       {Bold: true},
+	"italic":     {Italic: true},
+	"boldItalic": {Bold: true, Italic: true},
+	"monospace":  {Monospace: true},
+}
+
+var colorNames = []fyne.ThemeColorName{
+	theme.ColorNameBackground,
+	theme.ColorNameButton,
+	theme.ColorNameDisabledButton,
+	theme.ColorNameDisabled,
+	theme.ColorNameError,
+	theme.ColorNameFocus,
+	theme.ColorNameForeground,
+	theme.ColorNameForegroundOnError,
+	theme.ColorNameForegroundOnPrimary,
+	theme.ColorNameForegroundOnSuccess,
+	theme.ColorNameForegroundOnWarning,
+	theme.ColorNameHeaderBackground,
+	theme.ColorNameHover,
+	theme.ColorNameHyperlink,
+	theme.ColorNameInputBackground,
+	theme.ColorNameInputBorder,
+	theme.ColorNameMenuBackground,
+	theme.ColorNameOverlayBackground,
+	theme.ColorNamePlaceHolder,
+	theme.ColorNamePressed,
+	theme.ColorNamePrimary,
+	theme.ColorNameScrollBar,
+	theme.ColorNameScrollBarBackground,
+	theme.ColorNameSelection,
+	theme.ColorNameSeparator,
+	theme.ColorNameShadow,
+	theme.ColorNameSuccess,
+	theme.ColorNameWarning,
+}
+
+var sizeNames = []fyne.ThemeSizeName{
+	theme.SizeNameCaptionText,
+	theme.SizeNameInlineIcon,
+	theme.SizeNameInnerPadding,
+	theme.SizeNameLineSpacing,
+	theme.SizeNamePadding,
+	theme.SizeNameScrollBar,
+	theme.SizeNameScrollBarSmall,
+	theme.SizeNameSeparatorThickness,
+	theme.SizeNameText,
+	theme.SizeNameHeadingText,
+	theme.SizeNameSubHeadingText,
+	theme.SizeNameInputBorder,
+	theme.SizeNameInputRadius,
+	theme.SizeNameSelectionRadius,
+	theme.SizeNameScrollBarRadius,
+}
+
+// Sizes above this are almost certainly a typo and would make the window
+// unusable.
+const maxSize = 100
+
+// Load reads FileName from dir. It returns nil and no error when there is
+// no theme file. Every problem in the file is reported, each prefixed with
+// the key it is about.
+func Load(dir string) (*Theme, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file File
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t, err := file.Resolve(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Resolve checks every setting and reads the files it names from dir.
+func (f *File) Resolve(dir string) (*Theme, error) {
+	var errs []error
+	check := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	t := &Theme{
+		Colors: map[fyne.ThemeVariant]map[fyne.ThemeColorName]color.Color{},
+		Fonts:  map[fyne.TextStyle]fyne.Resource{},
+		Sizes:  map[fyne.ThemeSizeName]float32{},
+	}
+	for _, variantName := range sortedKeys(f.Colors) {
+		variant, ok := variants[variantName]
+		if !ok {
+			check("colors."+variantName, fmt.Errorf("unknown variant, must be dark or light"))
+			continue
+		}
+		t.Colors[variant] = map[fyne.ThemeColorName]color.Color{}
+		for _, name := range sortedKeys(f.Colors[variantName]) {
+			key := "colors." + variantName + "." + name
+			colorName, ok := lookup(colorNames, name)
+			if !ok {
+				check(key, fmt.Errorf("unknown color (known colors: %s)", join(colorNames)))
+				continue
+			}
+			c, err := ParseColor(f.Colors[variantName][name])
+			check(key, err)
+			if err == nil {
+				t.Colors[variant][colorName] = c
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(f.Fonts) {
+		key := "fonts." + name
+		style, ok := fontStyles[name]
+		if !ok {
+			check(key, fmt.Errorf("unknown font, must be regular, bold, italic, boldItalic or monospace"))
+			continue
+		}
+		font, err := loadFont(dir, f.Fonts[name])
+		check(key, err)
+		if err == nil {
+			t.Fonts[style] = font
+		}
+	}
+
+	for _, name := range sortedKeys(f.Sizes) {
+		key := "sizes." + name
+		sizeName, ok := lookup(sizeNames, name)
+		if !ok {
+			check(key, fmt.Errorf("unknown size (known sizes: %s)", join(sizeNames)))
+			continue
+		}
+		size := f.Sizes[name]
+		if size < 0 || size > maxSize {
+			check(key, fmt.Errorf("must be between 0 and %d, got %g", maxSize, size))
+			continue
+		}
+		t.Sizes[sizeName] = size
+	}
+
+	if f.Logo != "" {
+		logo, err := loadLogo(dir, f.Logo)
+		check("logo", err)
+		t.Logo = logo
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseColor accepts #rgb, #rrggbb and #rrggbbaa.
+func ParseColor(value string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(value, "#")
+	if ok && len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 8 || err != nil {
+		return nil, fmt.Errorf("must be a color such as \"#27b53d\" or \"#27b53d80\", got %q", value)
+	}
+	return color.NRGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+func loadFont(dir, name string) (fyne.Resource, error) {
+	data, err := readFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	// TrueType, OpenType and font collections all start with one of these.
+	for _, magic := range []string{"\x00\x01\x00\x00", "OTTO", "true", "ttcf"} {
+		if bytes.HasPrefix(data, []byte(magic)) {
+			return fyne.NewStaticResource(filepath.Base(name), data), nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a TrueType or OpenType font", name)
+}
+
+func loadLogo(dir, name string) (fyne.Resource, error) {
+	data, err := readFile(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".svg") {
+		if !bytes.Contains(data, []byte("<svg")) {
+			return nil, fmt.Errorf("%s is not an SVG image", name)
+		}
+	} else if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%s is not a PNG, JPEG or SVG image: %w", name, err)
+	}
+	return fyne.NewStaticResource(filepath.Base(name), data), nil
+}
+
+func readFile(dir, name string) ([]byte, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+func lookup[T ~string](names []T, name string) (T, bool) {
+	for _, n := range names {
+		if string(n) == name {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+func join[T ~string](names []T) string {
+	s := make([]string, len(names))
+	for i, n := range names {
+		s[i] = string(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
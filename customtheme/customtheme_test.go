@@ -0,0 +1,145 @@
+package customtheme
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+func writeFile(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadMissing(t *testing.T) {
+	got, err := Load(t.TempDir())
+	if got != nil || err != nil {
+		t.Fatalf("Load without a theme file = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "fonts/Brand.ttf", []byte("\x00\x01\x00\x00rest of the font"))
+	writeFile(t, dir, "brand.png", pngBytes(t))
+	writeFile(t, dir, FileName, []byte(`{
+		"colors": {
+			"dark": {"primary": "#f80", "background": "#10203040"},
+			"light": {"primary": "#ff8800"}
+		},
+		"fonts": {"regular": "fonts/Brand.ttf"},
+		"sizes": {"text": 15, "padding": 6},
+		"logo": "brand.png"
+	}`))
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orange := color.NRGBA{R: 0xff, G: 0x88, A: 0xff}
+	if c := got.Colors[theme.VariantDark][theme.ColorNamePrimary]; c != orange {
+		t.Errorf("dark primary = %v, want %v", c, orange)
+	}
+	if c := got.Colors[theme.VariantLight][theme.ColorNamePrimary]; c != orange {
+		t.Errorf("light primary = %v, want %v", c, orange)
+	}
+	if c := got.Colors[theme.VariantDark][theme.ColorNameBackground]; c != (color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x40}) {
+		t.Errorf("dark background = %v", c)
+	}
+	if font := got.Fonts[fyne.TextStyle{}]; font == nil || font.Name() != "Brand.ttf" {
+		t.Errorf("regular font = %v", font)
+	}
+	if got.Sizes[theme.SizeNameText] != 15 || got.Sizes[theme.SizeNamePadding] != 6 {
+		t.Errorf("sizes = %v", got.Sizes)
+	}
+	if got.Logo == nil || got.Logo.Name() != "brand.png" {
+		t.Errorf("logo = %v", got.Logo)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "notafont.ttf", []byte("hello"))
+	writeFile(t, dir, "logo.png", []byte("not a png"))
+	writeFile(t, dir, FileName, []byte(`{
+		"colors": {
+			"dim": {"primary": "#fff"},
+			"dark": {"primary": "green", "sparkle": "#fff"}
+		},
+		"fonts": {"regular": "notafont.ttf", "heading": "notafont.ttf", "bold": "missing.ttf"},
+		"sizes": {"text": 500, "bogus": 1},
+		"logo": "logo.png"
+	}`))
+
+	got, err := Load(dir)
+	if got != nil || err == nil {
+		t.Fatalf("Load = %v, %v; want an error", got, err)
+	}
+	for _, want := range []string{
+		"colors.dim: unknown variant",
+		`colors.dark.primary: must be a color such as "#27b53d"`,
+		"colors.dark.sparkle: unknown color",
+		"fonts.regular: notafont.ttf is not a TrueType or OpenType font",
+		"fonts.heading: unknown font",
+		"fonts.bold: failed to read missing.ttf",
+		"sizes.text: must be between 0 and 100, got 500",
+		"sizes.bogus: unknown size",
+		"logo: logo.png is not a PNG, JPEG or SVG image",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, FileName, []byte(`{"colour": {}}`))
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), `unknown field "colour"`) {
+		t.Errorf("Load = %v, want an unknown field error", err)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.Color
+	}{
+		{"#abc", color.NRGBA{R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff}},
+		{"#27B53D", color.NRGBA{R: 0x27, G: 0xb5, B: 0x3d, A: 0xff}},
+		{"#27b53d80", color.NRGBA{R: 0x27, G: 0xb5, B: 0x3d, A: 0x80}},
+		{"27b53d", nil},
+		{"#27b53", nil},
+		{"#ggg", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if (err == nil) != (tt.want != nil) || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/diagnostics"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/logging"
+	"sylicitybootstrapper/themecode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+)
+
+func diagnosticsFileName() string {
+	return fmt.Sprintf("sylicity-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+}
+
+// collectDiagnostics gathers what a bug report needs. cfg may be nil when
+// the config failed to load; the raw file is included instead.
+func collectDiagnostics(cfg *config.Config, opts LaunchOptions) diagnostics.Bundle {
+	report := diagnostics.Report{
+		Created:             time.Now(),
+		Session:             logging.SessionID,
+		BootstrapperVersion: buildVersion,
+		OS:                  runtime.GOOS,
+		Arch:                runtime.GOARCH,
+		Desktop:             os.Getenv("XDG_CURRENT_DESKTOP"),
+		SessionType:         os.Getenv("XDG_SESSION_TYPE"),
+		Theme:               "dark",
+	}
+	if themecode.DetectSystemTheme() == theme.VariantLight {
+		report.Theme = "light"
+	}
+
+	bundle := diagnostics.Bundle{Logs: map[string]string{}}
+	appDir, err := getAppDir()
+	if err == nil {
+		for _, path := range logging.Files(filepath.Join(appDir, "logs")) {
+			bundle.Logs["logs/"+filepath.Base(path)] = path
+		}
+	} else {
+		report.Notes = append(report.Notes, fmt.Sprintf("no app dir: %v", err))
+	}
+
+	if cfg == nil {
+		report.Notes = append(report.Notes, "configuration failed to load")
+		path := opts.ConfigPath
+		if path == "" && appDir != "" {
+			path = filepath.Join(appDir, config.FileName)
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			if bundle.Config, err = diagnostics.RedactConfig(data); err != nil {
+				report.Notes = append(report.Notes, fmt.Sprintf("configuration file left out: %v", err))
+			}
+		}
+		bundle.Report = report
+		return bundle
+	}
+
+	// Launch environment values are free-form and may hold credentials.
+	shared := *cfg
+	shared.Launch.Env = map[string]string{}
+	for key := range cfg.Launch.Env {
+		shared.Launch.Env[key] = "[redacted]"
+	}
+	bundle.Config, _ = json.MarshalIndent(shared, "", "  ")
+
+	if runtime.GOOS != "windows" {
+		report.Wine = diagnostics.WineVersion(cfg.Launch.Wrapper)
+	}
+
+	installer := newInstaller(cfg, nil)
+	for _, channel := range cfg.ChannelNames() {
+		versionsDir := cfg.VersionsDir(channel)
+		for _, year := range installer.InstalledYears(versionsDir) {
+			clientDir := install.ClientDir(versionsDir, year)
+			client := diagnostics.Client{
+				Channel:    channel,
+				Year:       year,
+				Executable: install.Installed(install.OS, clientDir),
+			}
+			if installed, err := install.ReadManifest(install.OS, clientDir); err == nil {
+				client.Version, client.Hash, client.Files = installed.Version, installed.Hash, len(installed.Files)
+			}
+			report.Clients = append(report.Clients, client)
+
+			logs, _ := filepath.Glob(filepath.Join(clientDir, "logs", "*"))
+			for _, path := range logs {
+				bundle.Logs[fmt.Sprintf("client-logs/%s-%s/%s", channel, year, filepath.Base(path))] = path
+			}
+		}
+	}
+
+	bundle.Report = report
+	return bundle
+}
+
+func exportDiagnostics(cfg *config.Config, opts LaunchOptions, path string) error {
+	if err := diagnostics.WriteFile(path, collectDiagnostics(cfg, opts)); err != nil {
+		slog.Error("exporting diagnostics failed", "path", path, "err", err)
+		return err
+	}
+	slog.Info("exported diagnostics", "path", path)
+	return nil
+}
+
+// showExportDiagnostics saves the bundle to the desktop, or the home folder
+// without one, and tells the user where it went.
+func showExportDiagnostics(cfg *config.Config, opts LaunchOptions, win fyne.Window) {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		dialog.ShowError(err, win)
+		return
+	}
+	if desktop := filepath.Join(dir, "Desktop"); isDir(desktop) {
+		dir = desktop
+	}
+	path := filepath.Join(dir, diagnosticsFileName())
+
+	go func() {
+		err := exportDiagnostics(cfg, opts, path)
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s: %w", l10n.T("diagnostics.failed"), err), win)
+				return
+			}
+			dialog.ShowInformation(l10n.T("diagnostics.exported"), l10n.T("diagnostics.savedTo", "Path", path), win)
+		})
+	}()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
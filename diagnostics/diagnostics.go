@@ -0,0 +1,204 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Only the tail of each log goes into the bundle.
+const maxLogBytes = 2 << 20
+
+type Client struct {
+	Channel    string `json:"channel"`
+	Year       string `json:"year"`
+	Version    string `json:"version,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	Files      int    `json:"files"`
+	Executable bool   `json:"executable"`
+}
+
+type Report struct {
+	Created             time.Time `json:"created"`
+	Session             string    `json:"session"`
+	BootstrapperVersion string    `json:"bootstrapperVersion"`
+	OS                  string    `json:"os"`
+	Arch                string    `json:"arch"`
+	Desktop             string    `json:"desktop,omitempty"`
+	SessionType         string    `json:"sessionType,omitempty"`
+	Theme               string    `json:"theme,omitempty"`
+	Wine                string    `json:"wine,omitempty"`
+	Clients             []Client  `json:"clients"`
+	Notes               []string  `json:"notes,omitempty"`
+}
+
+// Bundle is everything that goes into the zip. Logs maps the name inside the
+// zip to the file on disk.
+type Bundle struct {
+	Report Report
+	Config []byte
+	Logs   map[string]string
+}
+
+func WriteFile(path string, b Bundle) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(out, b); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
+
+// Write zips b into w. Every text file goes through Redact on the way in.
+func Write(w io.Writer, b Bundle) error {
+	zw := zip.NewWriter(w)
+
+	report, err := json.MarshalIndent(b.Report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addFile(zw, "report.json", report); err != nil {
+		return err
+	}
+	if b.Config != nil {
+		if err := addFile(zw, "config.json", b.Config); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(b.Logs))
+	for name := range b.Logs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := readTail(b.Logs[name], maxLogBytes)
+		if err != nil {
+			data = []byte(fmt.Sprintf("could not read %s: %v\n", filepath.Base(b.Logs[name]), err))
+		}
+		if err := addFile(zw, name, data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func addFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write([]byte(Redact(string(data))))
+	return err
+}
+
+func readTail(path string, limit int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		if _, err := f.Seek(-limit, io.SeekEnd); err != nil {
+			return nil, err
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > limit {
+		// Drop the partial first line.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+		data = append([]byte("[earlier lines omitted]\n"), data...)
+	}
+	return data, nil
+}
+
+const redacted = "[redacted]"
+
+// RedactConfig blanks the launch.env values in a config file that may not
+// load, e.g. because it fails validation. Those values are free-form and may
+// hold credentials. Files that are not JSON objects are rejected, since they
+// cannot be redacted reliably.
+func RedactConfig(data []byte) ([]byte, error) {
+	var file map[string]any
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if launch, ok := file["launch"].(map[string]any); ok {
+		switch env := launch["env"].(type) {
+		case map[string]any:
+			for key := range env {
+				env[key] = redacted
+			}
+		case nil:
+		default:
+			launch["env"] = redacted
+		}
+	}
+	return json.MarshalIndent(file, "", "  ")
+}
+
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(--authenticationTicket[ =]+)[^\s"]+`),
+	regexp.MustCompile(`(?i)(gameinfo:)[^+\s"]+`),
+	regexp.MustCompile(`(?i)(authorization:?\s*(?:bearer|basic)?\s*)[^\s"]+`),
+	regexp.MustCompile(`(?i)(\.ROBLOSECURITY=?)[^;\s"]+`),
+	regexp.MustCompile(`(?i)(://)[^/\s:@"]+:[^/\s@"]+(@)`),
+	regexp.MustCompile(`(?i)((?:ticket|token|password|passwd|secret|cookie|apikey|api_key)["']?\s*[:=]\s*["']?)[^\s"'&,;]+`),
+}
+
+// Redact blanks out auth tickets, tokens, passwords and credentials in URLs,
+// and replaces the home directory with ~.
+func Redact(text string) string {
+	for _, pattern := range secretPatterns {
+		if pattern.NumSubexp() == 2 {
+			text = pattern.ReplaceAllString(text, "${1}"+redacted+"${2}")
+		} else {
+			text = pattern.ReplaceAllString(text, "${1}"+redacted)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		text = strings.ReplaceAll(text, home, "~")
+	}
+	return text
+}
+
+// WineVersion asks the Wine binary the client is launched through for its
+// version. It returns "" when Wine is not in use or not installed.
+func WineVersion(wrapper []string) string {
+	wine := "wine"
+	if len(wrapper) > 0 {
+		if !strings.Contains(strings.ToLower(filepath.Base(wrapper[0])), "wine") {
+			return ""
+		}
+		wine = wrapper[0]
+	}
+	out, err := exec.Command(wine, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
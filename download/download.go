@@ -0,0 +1,208 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"sylicitybootstrapper/l10n"
+)
+
+// Source opens remote files. HTTPSource is the real one; tests can serve
+// from anywhere else.
+type Source interface {
+	// Open returns the body at url and its size, or -1 when unknown.
+	Open(url string) (io.ReadCloser, int64, error)
+}
+
+type HTTPSource struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// Default is what the bootstrapper uses unless told otherwise.
+var Default Source = HTTPSource{UserAgent: "Roblox/WinInet"}
+
+func (s HTTPSource) Open(url string) (io.ReadCloser, int64, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if s.UserAgent != "" {
+		req.Header.Set("User-Agent", s.UserAgent)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// Fetch copies url from src into w. Bodies shorter than the size the source
+// announced are reported as io.ErrUnexpectedEOF.
+func Fetch(src Source, url string, w io.Writer, onProgress func(TransferProgress)) error {
+	body, size, err := src.Open(url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if onProgress == nil {
+		onProgress = func(TransferProgress) {}
+	}
+	writer := &ProgressWriter{
+		Total:      size,
+		W:          w,
+		OnProgress: onProgress,
+	}
+
+	_, err = io.Copy(writer, body)
+	writer.Finish()
+	if err == nil && size > 0 && writer.Written < size {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// TransferProgress describes a running download. Total is -1 when the
+// server did not send a Content-Length.
+type TransferProgress struct {
+	Done  int64
+	Total int64
+	Rate  float64
+	ETA   time.Duration
+}
+
+// Fraction returns the completed fraction, or -1 when the size is unknown.
+func (tp TransferProgress) Fraction() float32 {
+	if tp.Total <= 0 {
+		return -1
+	}
+	return float32(tp.Done) / float32(tp.Total)
+}
+
+// String formats the transfer as "142/310 MB, 8.4 MB/s, 20s left".
+func (tp TransferProgress) String() string {
+	var parts []string
+	if tp.Total > 0 {
+		unit, div := byteUnit(tp.Total)
+		parts = append(parts, fmt.Sprintf("%s/%s %s", formatAmount(float64(tp.Done)/div), formatAmount(float64(tp.Total)/div), unit))
+	} else {
+		unit, div := byteUnit(tp.Done)
+		parts = append(parts, fmt.Sprintf("%s %s", formatAmount(float64(tp.Done)/div), unit))
+	}
+	if tp.Rate > 0 {
+		unit, div := byteUnit(int64(tp.Rate))
+		parts = append(parts, fmt.Sprintf("%s %s/s", formatAmount(tp.Rate/div), unit))
+	}
+	if tp.ETA > 0 {
+		parts = append(parts, l10n.T("download.timeLeft", "Time", formatETA(tp.ETA)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func byteUnit(n int64) (string, float64) {
+	switch {
+	case n >= 1<<30:
+		return "GB", 1 << 30
+	case n >= 1<<20:
+		return "MB", 1 << 20
+	case n >= 1<<10:
+		return "KB", 1 << 10
+	default:
+		return "B", 1
+	}
+}
+
+func formatAmount(v float64) string {
+	if v >= 100 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func formatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
+const (
+	progressInterval = 100 * time.Millisecond
+	rateSampleWindow = 500 * time.Millisecond
+	rateSmoothing    = 0.3
+)
+
+type ProgressWriter struct {
+	Total      int64
+	Written    int64
+	W          io.Writer
+	OnProgress func(TransferProgress)
+
+	rate          float64
+	sampleStart   time.Time
+	sampleWritten int64
+	lastReport    time.Time
+}
+
+func (pw *ProgressWriter) Write(p []byte) (int, error) {
+	n, err := pw.W.Write(p)
+	if err == nil {
+		pw.Written += int64(n)
+
+		now := time.Now()
+		if pw.sampleStart.IsZero() {
+			pw.sampleStart = now
+		}
+		// Exponentially smoothed rate over fixed windows, so a single slow
+		// read does not make the ETA jump around.
+		if elapsed := now.Sub(pw.sampleStart); elapsed >= rateSampleWindow {
+			sample := float64(pw.Written-pw.sampleWritten) / elapsed.Seconds()
+			if pw.rate == 0 {
+				pw.rate = sample
+			} else {
+				pw.rate = rateSmoothing*sample + (1-rateSmoothing)*pw.rate
+			}
+			pw.sampleStart, pw.sampleWritten = now, pw.Written
+		}
+
+		if now.Sub(pw.lastReport) >= progressInterval {
+			pw.lastReport = now
+			pw.OnProgress(pw.progress())
+		}
+	}
+	return n, err
+}
+
+// Finish reports the final state, which the throttling in Write may have skipped.
+func (pw *ProgressWriter) Finish() {
+	pw.OnProgress(pw.progress())
+}
+
+func (pw *ProgressWriter) progress() TransferProgress {
+	tp := TransferProgress{Done: pw.Written, Total: pw.Total, Rate: pw.rate}
+	if tp.Total <= 0 {
+		tp.Total = -1
+	}
+	if pw.rate > 0 && pw.Total > pw.Written {
+		tp.ETA = time.Duration(float64(pw.Total-pw.Written) / pw.rate * float64(time.Second))
+	}
+	return tp
+}
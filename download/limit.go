@@ -0,0 +1,99 @@
+package download
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// limitChunk caps a single read, so a slow limit is spread evenly rather
+// than arriving in large bursts.
+const limitChunk = 16 << 10
+
+// Limiter is a token bucket holding up to one second of transfer. Every
+// download wrapped with the same Limiter draws from the one bucket, so the
+// limit holds for all connections together.
+type Limiter struct {
+	// rate returns the limit in bytes per second at a given time; 0 or less
+	// means unlimited.
+	rate func(now time.Time) int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewLimiter returns a limiter that asks rate for the current limit, which
+// lets it follow a schedule.
+func NewLimiter(rate func(now time.Time) int64) *Limiter {
+	return &Limiter{rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+// Wait takes n bytes from the bucket and blocks until the bucket is no
+// longer in debt.
+func (l *Limiter) Wait(n int) {
+	if d := l.take(n); d > 0 {
+		l.sleep(d)
+	}
+}
+
+func (l *Limiter) take(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	rate := float64(l.rate(now))
+	if rate <= 0 {
+		l.last = time.Time{}
+		return 0
+	}
+	if l.last.IsZero() {
+		l.tokens = rate
+	} else {
+		l.tokens = min(rate, l.tokens+now.Sub(l.last).Seconds()*rate)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / rate * float64(time.Second))
+}
+
+// Limit wraps src so every body it opens is read through limiter.
+func Limit(src Source, limiter *Limiter) Source {
+	return limitedSource{src: src, limiter: limiter}
+}
+
+type limitedSource struct {
+	src     Source
+	limiter *Limiter
+}
+
+func (s limitedSource) Open(url string) (io.ReadCloser, int64, error) {
+	body, size, err := s.src.Open(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &limitedReader{ReadCloser: body, limiter: s.limiter}, size, nil
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	limiter *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > limitChunk {
+		p = p[:limitChunk]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.limiter.Wait(n)
+	}
+	return n, err
+}
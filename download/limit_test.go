@@ -0,0 +1,109 @@
+package download
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when the limiter sleeps.
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func newFakeLimiter(rate func(time.Time) int64) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(rate)
+	l.now = func() time.Time { return clock.now }
+	l.sleep = func(d time.Duration) {
+		clock.now = clock.now.Add(d)
+		clock.slept += d
+	}
+	return l, clock
+}
+
+type memSource map[string][]byte
+
+func (s memSource) Open(url string) (io.ReadCloser, int64, error) {
+	return io.NopCloser(bytes.NewReader(s[url])), int64(len(s[url])), nil
+}
+
+func TestLimiterPacesReads(t *testing.T) {
+	const rate = 10 << 10
+	l, clock := newFakeLimiter(func(time.Time) int64 { return rate })
+	src := Limit(memSource{"zip": make([]byte, 5*rate)}, l)
+
+	var out bytes.Buffer
+	if err := Fetch(src, "zip", &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 5*rate {
+		t.Fatalf("read %d bytes, want %d", out.Len(), 5*rate)
+	}
+	// The first second comes out of the full bucket.
+	if want := 4 * time.Second; clock.slept < want-10*time.Millisecond || clock.slept > want+10*time.Millisecond {
+		t.Errorf("slept %v, want about %v", clock.slept, want)
+	}
+}
+
+func TestLimiterFollowsSchedule(t *testing.T) {
+	limited := true
+	l, clock := newFakeLimiter(func(time.Time) int64 {
+		if limited {
+			return 1000
+		}
+		return 0
+	})
+
+	l.Wait(1000)
+	l.Wait(500)
+	if clock.slept != 500*time.Millisecond {
+		t.Fatalf("slept %v while limited, want 500ms", clock.slept)
+	}
+
+	limited = false
+	clock.slept = 0
+	l.Wait(1 << 20)
+	if clock.slept != 0 {
+		t.Fatalf("slept %v while unlimited", clock.slept)
+	}
+
+	// Coming back from unlimited starts with a full bucket.
+	limited = true
+	l.Wait(1000)
+	if clock.slept != 0 {
+		t.Fatalf("slept %v right after the limit came back", clock.slept)
+	}
+}
+
+func TestLimiterIsSharedAcrossDownloads(t *testing.T) {
+	const rate = 400 << 10
+	l := NewLimiter(func(time.Time) int64 { return rate })
+	src := Limit(memSource{"zip": make([]byte, 200<<10)}, l)
+
+	// Alone, each download fits into the bucket; together they need half a
+	// second more than it holds.
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Fetch(src, "zip", io.Discard, nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("three downloads took %v, want about 500ms", elapsed)
+	}
+}
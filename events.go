@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"sylicitybootstrapper/launch"
+	"sylicitybootstrapper/progress"
+)
+
+type installerEventKind int
+
+const (
+	// eventStatus shows Message while the loader animates.
+	eventStatus installerEventKind = iota
+	eventProgress
+	eventFailed
+	// eventDone ends the run. Repairable offers to repair what was found.
+	eventDone
+	// eventLaunched means the client was started and the window can go.
+	eventLaunched
+	// eventCrashed means a supervised client quit on start-up; see Crash.
+	eventCrashed
+)
+
+// installerEvent is how the installer, verify and repair logic talk to a
+// front end. None of it touches widgets, so it can run on any goroutine.
+type installerEvent struct {
+	Kind       installerEventKind
+	Message    string
+	Progress   progress.Snapshot
+	Err        error
+	Repairable bool
+	Crash      *launch.Exit
+}
+
+func statusEvent(message string) installerEvent {
+	return installerEvent{Kind: eventStatus, Message: message}
+}
+
+func failedEvent(context string, err error) installerEvent {
+	return installerEvent{Kind: eventFailed, Message: fmt.Sprintf("%s: %v", context, err), Err: err}
+}
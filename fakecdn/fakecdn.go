@@ -0,0 +1,207 @@
+// Package fakecdn is an in-process stand-in for the Sylicity CDN and client
+// versions API, for tests. It serves a manifest, client zips and per-file
+// downloads, and can be told to misbehave on any path.
+package fakecdn
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sylicitybootstrapper/manifest"
+)
+
+const ManifestPath = "/v1/client-versions"
+
+// Fault changes how one path is served. The zero Fault serves normally.
+type Fault struct {
+	// Status, if set, is sent with an empty body instead of the content.
+	Status int
+	// Delay holds the response back before the headers are written.
+	Delay time.Duration
+	// Truncate, if positive, cuts the body off after that many bytes while
+	// still announcing the full Content-Length.
+	Truncate int
+	// Corrupt flips a byte of the body, so it no longer matches its hash.
+	Corrupt bool
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	clients  map[string]manifest.Client
+	content  map[string][]byte
+	faults   map[string]Fault
+	requests map[string]int
+}
+
+func New() *Server {
+	s := &Server{
+		clients:  map[string]manifest.Client{},
+		content:  map[string][]byte{},
+		faults:   map[string]Fault{},
+		requests: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *Server) ManifestURL() string {
+	return s.URL + ManifestPath
+}
+
+func ZipPath(year string) string {
+	return fmt.Sprintf("/clients/Client%s.zip", year)
+}
+
+func FilePath(year, name string) string {
+	return fmt.Sprintf("/files/Client%s/%s", year, name)
+}
+
+// AddClient publishes a client made of files, keyed by slash-separated path.
+// Names listed in executable are stored with mode 0755.
+func (s *Server) AddClient(year, version string, files map[string][]byte, executable ...string) (manifest.Client, error) {
+	exec := map[string]bool{}
+	for _, name := range executable {
+		exec[name] = true
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	var entries []manifest.File
+	for _, name := range names {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0644)
+		if exec[name] {
+			header.SetMode(0755)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return manifest.Client{}, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return manifest.Client{}, err
+		}
+		entries = append(entries, manifest.File{Path: name, SHA1: sha1Hex(files[name]), Size: int64(len(files[name]))})
+	}
+	if err := zw.Close(); err != nil {
+		return manifest.Client{}, err
+	}
+
+	client := manifest.Client{
+		Hash:     sha1Hex(buf.Bytes()),
+		URL:      s.URL + ZipPath(year),
+		Version:  version,
+		Files:    entries,
+		FilesURL: s.URL + fmt.Sprintf("/files/Client%s", year),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[year] = client
+	s.content[ZipPath(year)] = buf.Bytes()
+	for name, data := range files {
+		s.content[FilePath(year, name)] = data
+	}
+	return client, nil
+}
+
+// SetClient replaces the manifest entry for year, e.g. to advertise a wrong
+// hash or drop the per-file listing.
+func (s *Server) SetClient(year string, client manifest.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[year] = client
+}
+
+// Put serves data at path, e.g. for a file the manifest should not list
+// through AddClient.
+func (s *Server) Put(path string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content[path] = data
+}
+
+func (s *Server) Fail(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = fault
+}
+
+func (s *Server) Clear(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.faults, path)
+}
+
+// Requests returns how often path was requested.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	fault := s.faults[r.URL.Path]
+	var body []byte
+	found := true
+	if r.URL.Path == ManifestPath {
+		body, _ = json.Marshal(manifest.Response{Clients: s.clients})
+	} else {
+		body, found = s.content[r.URL.Path]
+	}
+	s.mu.Unlock()
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault.Status != 0 {
+		w.WriteHeader(fault.Status)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	if fault.Corrupt && len(body) > 0 {
+		body = bytes.Clone(body)
+		body[len(body)/2] ^= 0xFF
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if strings.HasSuffix(r.URL.Path, ".zip") {
+		w.Header().Set("Content-Type", "application/zip")
+	}
+	if fault.Truncate > 0 && fault.Truncate < len(body) {
+		body = body[:fault.Truncate]
+	}
+	w.Write(body)
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+// Package filelock provides advisory file locks shared between processes.
+// They only keep out processes that lock the same file through this package.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned by TryAcquire when another process holds a
+// conflicting lock.
+var ErrLocked = errors.New("locked by another process")
+
+type Mode int
+
+const (
+	// Shared locks can be held by any number of processes at once.
+	Shared Mode = iota
+	// Exclusive locks keep out every other lock on the file.
+	Exclusive
+)
+
+// Lock is a held lock. Release it when done; it is also dropped when the
+// process exits.
+type Lock struct {
+	f *os.File
+}
+
+// TryAcquire locks path without waiting. The file is created if needed.
+func TryAcquire(path string, mode Mode) (*Lock, error) {
+	return acquire(path, mode, false)
+}
+
+// Acquire locks path, waiting for other processes to release it first.
+func Acquire(path string, mode Mode) (*Lock, error) {
+	return acquire(path, mode, true)
+}
+
+func acquire(path string, mode Mode, wait bool) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f, mode, wait); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+func (l *Lock) Release() error {
+	err := unlockFile(l.f)
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Close drops this process's handle without unlocking. Use it instead of
+// Release once the lock has been passed to a child, since unlocking would
+// drop it for the child too.
+func (l *Lock) Close() error {
+	return l.f.Close()
+}
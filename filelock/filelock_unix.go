@@ -0,0 +1,41 @@
+//go:build unix
+
+package filelock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func lockFile(f *os.File, mode Mode, wait bool) error {
+	how := syscall.LOCK_SH
+	if mode == Exclusive {
+		how = syscall.LOCK_EX
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		switch {
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.EWOULDBLOCK):
+			return ErrLocked
+		}
+		return err
+	}
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// PassTo makes cmd inherit the lock, so it stays held until the child exits
+// even if this process exits first. It reports whether the lock was passed.
+func (l *Lock) PassTo(cmd *exec.Cmd) bool {
+	cmd.ExtraFiles = append(cmd.ExtraFiles, l.f)
+	return true
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+func lockFile(f *os.File, mode Mode, wait bool) error {
+	var flags uint32
+	if mode == Exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
+
+// PassTo reports false: a child process cannot inherit a LockFileEx lock, so
+// the caller has to hold it until the child exits.
+func (l *Lock) PassTo(cmd *exec.Cmd) bool {
+	return false
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/instance"
+	"sylicitybootstrapper/l10n"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// claimInstance makes this the primary bootstrapper for the app dir. If
+// another one already runs, opts is handed to it and forwarded is true.
+func claimInstance(opts LaunchOptions) (primary *instance.Primary, forwarded bool) {
+	appDir, err := getAppDir()
+	if err != nil {
+		slog.Warn("could not claim single instance", "err", err)
+		return nil, false
+	}
+	primary, err = instance.Acquire(appDir)
+	if errors.Is(err, instance.ErrRunning) {
+		err := instance.Forward(appDir, opts)
+		if errors.Is(err, instance.ErrRefused) {
+			slog.Error("the running bootstrapper refused the request", "err", err)
+			fmt.Fprintln(os.Stderr, err)
+			return nil, true
+		}
+		if err != nil {
+			slog.Error("could not hand request to the running bootstrapper", "err", err)
+			return nil, false
+		}
+		slog.Info("handed request to the running bootstrapper", "options", opts)
+		return nil, true
+	}
+	if err != nil {
+		slog.Warn("could not claim single instance", "err", err)
+	}
+	return primary, false
+}
+
+// requestQueue holds requests forwarded by later invocations until the
+// window is free. Its methods must run on the UI goroutine.
+type requestQueue struct {
+	win  fyne.Window
+	view *installerView
+	// cfg is what every request in this window runs with.
+	cfg *config.Config
+	// start opens a request in the window, like the initial one.
+	start   func(opts LaunchOptions)
+	pending []LaunchOptions
+}
+
+// serve feeds requests forwarded to primary into q.
+func (q *requestQueue) serve(primary *instance.Primary) {
+	primary.Serve(func(request json.RawMessage) error {
+		var opts LaunchOptions
+		if err := json.Unmarshal(request, &opts); err != nil {
+			slog.Warn("ignoring malformed forwarded request", "err", err)
+			return fmt.Errorf("malformed request: %w", err)
+		}
+		if err := q.check(opts); err != nil {
+			slog.Warn("refusing forwarded request", "options", opts, "err", err)
+			return err
+		}
+		slog.Info("received forwarded request", "options", opts)
+		fyne.Do(func() { q.push(opts) })
+		return nil
+	})
+}
+
+// check rejects requests this window cannot carry out as asked. It already
+// runs with its own configuration, so overrides could only be dropped.
+func (q *requestQueue) check(opts LaunchOptions) error {
+	if opts.ConfigPath != "" || len(opts.ConfigOverrides) > 0 {
+		return errors.New("configuration overrides cannot be passed to a running bootstrapper; close it first")
+	}
+	if _, err := q.cfg.ManifestURL(opts.Channel); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (q *requestQueue) push(opts LaunchOptions) {
+	q.win.RequestFocus()
+	switch {
+	case q.view.running:
+		q.pending = append(q.pending, opts)
+		slog.Info("queued forwarded request", "waiting", len(q.pending))
+	case q.win.Content() != q.view.content:
+		// The client manager or uninstall screen may be halfway through
+		// something, so only leave it when asked to.
+		dialog.ShowConfirm(l10n.T("request.title"), l10n.T("request.confirm", "Request", describeMode(opts.LaunchMode)), func(ok bool) {
+			if ok {
+				q.start(opts)
+			}
+		}, q.win)
+	default:
+		q.start(opts)
+	}
+}
+
+func (q *requestQueue) waiting() int {
+	if q == nil {
+		return 0
+	}
+	return len(q.pending)
+}
+
+// next starts the oldest waiting request and reports whether there was one.
+func (q *requestQueue) next() bool {
+	if q.waiting() == 0 {
+		return false
+	}
+	opts := q.pending[0]
+	q.pending = q.pending[1:]
+	q.start(opts)
+	return true
+}
+
+func describeMode(mode string) string {
+	switch mode {
+	case "play":
+		return l10n.T("request.play")
+	case "manage":
+		return l10n.T("request.manage")
+	case "verify":
+		return l10n.T("request.verify")
+	case "repair":
+		return l10n.T("request.repair")
+	case "uninstall":
+		return l10n.T("request.uninstall")
+	}
+	return l10n.T("request.install")
+}
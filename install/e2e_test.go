@@ -0,0 +1,390 @@
+package install_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sylicitybootstrapper/download"
+	"sylicitybootstrapper/fakecdn"
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/launch"
+	"sylicitybootstrapper/manifest"
+	"sylicitybootstrapper/progress"
+)
+
+// The stub client records its arguments where the test tells it to.
+const stubClient = "#!/bin/sh\necho \"$@\" > \"$SYLICITY_STUB_OUT\"\n"
+
+func newCDN(t *testing.T) *fakecdn.Server {
+	t.Helper()
+	cdn := fakecdn.New()
+	t.Cleanup(cdn.Close)
+
+	_, err := cdn.AddClient("2016", "0.2016.1", map[string][]byte{
+		install.ExeName:           []byte(stubClient),
+		"content/fonts/arial.ttf": []byte(strings.Repeat("font", 4096)),
+		"content/sky/sky512.tex":  []byte(strings.Repeat("sky", 8192)),
+	}, install.ExeName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cdn
+}
+
+type recorder struct {
+	mu        sync.Mutex
+	snapshots []progress.Snapshot
+}
+
+func (r *recorder) add(s progress.Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshots = append(r.snapshots, s)
+}
+
+func (r *recorder) last() progress.Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.snapshots) == 0 {
+		return progress.Snapshot{}
+	}
+	return r.snapshots[len(r.snapshots)-1]
+}
+
+// downloadDir is shared by every installer in the tests, so leftoverZips
+// sees what any of them left behind.
+var downloadDir string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "sylicity-downloads-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	downloadDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newInstaller(src download.Source, stages []progress.Stage) (*install.Installer, *recorder) {
+	rec := &recorder{}
+	return &install.Installer{
+		Source:      src,
+		FS:          install.OS,
+		Tracker:     progress.NewTracker(stages, rec.add),
+		Concurrency: 2,
+		DownloadDir: downloadDir,
+	}, rec
+}
+
+func fetch(t *testing.T, in *install.Installer, cdn *fakecdn.Server) map[string]manifest.Client {
+	t.Helper()
+	clients, err := in.Manifest(cdn.ManifestURL())
+	if err != nil {
+		t.Fatalf("Manifest: %v", err)
+	}
+	return clients
+}
+
+// leftoverZips lists the temporary client zips the installer did not clean up.
+func leftoverZips(t *testing.T) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(downloadDir, "client-*.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestInstallVerifyLaunch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the stub client is a shell script")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	cdn := newCDN(t)
+	versionsDir := t.TempDir()
+
+	in, rec := newInstaller(download.HTTPSource{}, progress.InstallStages[:len(progress.InstallStages)-1])
+	clients := fetch(t, in, cdn)
+	if err := in.Sync(versionsDir, clients, false); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got := rec.last().Overall; got != 1 {
+		t.Errorf("overall progress after install = %v, want 1", got)
+	}
+	if zips := leftoverZips(t); len(zips) > 0 {
+		t.Errorf("temporary zips left behind: %v", zips)
+	}
+
+	clientDir := install.ClientDir(versionsDir, "2016")
+	installed, err := install.ReadManifest(install.OS, clientDir)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if installed.Version != "0.2016.1" || len(installed.Files) != 3 {
+		t.Errorf("installed manifest = %+v", installed)
+	}
+
+	verifier, _ := newInstaller(download.HTTPSource{}, progress.VerifyStages)
+	results, err := verifier.VerifyAll(versionsDir, clients, "", false)
+	if err != nil {
+		t.Fatalf("VerifyAll: %v", err)
+	}
+	if len(results) != 1 || !results[0].OK() || results[0].Checked != 3 {
+		t.Fatalf("verify results = %+v", results)
+	}
+
+	out := filepath.Join(t.TempDir(), "args")
+	err = launch.Client(launch.ExecSpawner{}, launch.Options{
+		Executable: filepath.Join(clientDir, install.ExeName),
+		Play:       true,
+		AuthURL:    "http://auth.invalid/negotiate",
+		AuthTicket: "ticket123",
+		JoinScript: "http://game.invalid/join",
+		Env:        map[string]string{"SYLICITY_STUB_OUT": out},
+	})
+	if err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+
+	var args []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if args, err = os.ReadFile(out); err == nil && len(args) > 0 {
+			break
+		}
+	}
+	want := "--play --authenticationUrl http://auth.invalid/negotiate --authenticationTicket ticket123 --joinScriptUrl http://game.invalid/join"
+	if got := strings.TrimSpace(string(args)); got != want {
+		t.Errorf("stub client got args %q, want %q", got, want)
+	}
+}
+
+func TestSyncSkipsInstalledClients(t *testing.T) {
+	cdn := newCDN(t)
+	versionsDir := t.TempDir()
+
+	in, _ := newInstaller(download.HTTPSource{}, progress.ClientStages)
+	clients := fetch(t, in, cdn)
+	for i := 0; i < 2; i++ {
+		if err := in.Sync(versionsDir, clients, false); err != nil {
+			t.Fatalf("Sync #%d: %v", i+1, err)
+		}
+	}
+	if got := cdn.Requests(fakecdn.ZipPath("2016")); got != 1 {
+		t.Errorf("zip requested %d times, want 1", got)
+	}
+
+	if err := in.Sync(versionsDir, clients, true); err != nil {
+		t.Fatalf("forced Sync: %v", err)
+	}
+	if got := cdn.Requests(fakecdn.ZipPath("2016")); got != 2 {
+		t.Errorf("zip requested %d times after a forced sync, want 2", got)
+	}
+}
+
+func TestInstallWaitsForClientLock(t *testing.T) {
+	cdn := newCDN(t)
+	clientDir := install.ClientDir(t.TempDir(), "2016")
+
+	in, rec := newInstaller(download.HTTPSource{}, progress.ClientStages)
+	clients := fetch(t, in, cdn)
+
+	// A launch elsewhere holds the client; the install must wait for it.
+	launchLock, err := install.LockClient(clientDir, filelock.Shared, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- in.Client(clientDir, "2016", clients["2016"]) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rec.last().Message != install.WaitingMessage() {
+		if time.Now().After(deadline) {
+			t.Fatalf("installer did not report waiting, last message %q", rec.last().Message)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if install.Installed(install.OS, clientDir) {
+		t.Fatal("client was installed while another process held it")
+	}
+
+	launchLock.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if !install.Installed(install.OS, clientDir) {
+		t.Error("client not installed after the lock was released")
+	}
+}
+
+func TestRepair(t *testing.T) {
+	for _, perFile := range []bool{true, false} {
+		name := "zip"
+		if perFile {
+			name = "per-file"
+		}
+		t.Run(name, func(t *testing.T) {
+			cdn := newCDN(t)
+			versionsDir := t.TempDir()
+			clientDir := install.ClientDir(versionsDir, "2016")
+
+			in, _ := newInstaller(download.HTTPSource{}, progress.ClientStages)
+			clients := fetch(t, in, cdn)
+			if err := in.Sync(versionsDir, clients, false); err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			if !perFile {
+				client := clients["2016"]
+				client.FilesURL = ""
+				clients["2016"] = client
+			}
+
+			os.Remove(filepath.Join(clientDir, "content", "fonts", "arial.ttf"))
+			os.WriteFile(filepath.Join(clientDir, "content", "sky", "sky512.tex"), []byte("tampered"), 0644)
+			os.WriteFile(filepath.Join(clientDir, "user.txt"), []byte("mine"), 0644)
+			// Logs of a supervised client are not extra files.
+			os.MkdirAll(install.LogDir(clientDir), 0755)
+			os.WriteFile(filepath.Join(install.LogDir(clientDir), "client-1.log"), []byte("log"), 0644)
+
+			results, err := in.VerifyAll(versionsDir, clients, "2016", false)
+			if err != nil {
+				t.Fatalf("VerifyAll: %v", err)
+			}
+			result := results[0]
+			if len(result.Missing) != 1 || len(result.Modified) != 1 || len(result.Extra) != 1 {
+				t.Fatalf("verify result = %+v", result)
+			}
+
+			results, err = in.VerifyAll(versionsDir, clients, "2016", true)
+			if err != nil {
+				t.Fatalf("repair: %v", err)
+			}
+			if !results[0].OK() {
+				t.Fatalf("client still broken after repair: %+v", results[0])
+			}
+			if _, err := os.Stat(filepath.Join(clientDir, "user.txt")); err != nil {
+				t.Errorf("repair removed an extra file: %v", err)
+			}
+
+			zipRequests := cdn.Requests(fakecdn.ZipPath("2016"))
+			fileRequests := cdn.Requests(fakecdn.FilePath("2016", "content/fonts/arial.ttf"))
+			if perFile && (zipRequests != 1 || fileRequests != 1) {
+				t.Errorf("per-file repair made %d zip and %d file requests, want 1 and 1", zipRequests, fileRequests)
+			}
+			if !perFile && (zipRequests != 2 || fileRequests != 0) {
+				t.Errorf("zip repair made %d zip and %d file requests, want 2 and 0", zipRequests, fileRequests)
+			}
+		})
+	}
+}
+
+func TestInstallFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault fakecdn.Fault
+		want  string
+	}{
+		{"server error", fakecdn.Fault{Status: http.StatusInternalServerError}, "500"},
+		{"truncated body", fakecdn.Fault{Truncate: 100}, "unexpected EOF"},
+		{"bad hash", fakecdn.Fault{Corrupt: true}, "corrupt"},
+		{"slow response", fakecdn.Fault{Delay: 2 * time.Second}, "Timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+			cdn := newCDN(t)
+			versionsDir := t.TempDir()
+
+			src := download.HTTPSource{Client: &http.Client{Timeout: 300 * time.Millisecond}}
+			in, _ := newInstaller(src, progress.ClientStages)
+			clients := fetch(t, in, cdn)
+			cdn.Fail(fakecdn.ZipPath("2016"), tt.fault)
+
+			err := in.Sync(versionsDir, clients, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Sync error = %v, want one mentioning %q", err, tt.want)
+			}
+			if install.Installed(install.OS, install.ClientDir(versionsDir, "2016")) {
+				t.Error("client reported as installed after a failed download")
+			}
+			if zips := leftoverZips(t); len(zips) > 0 {
+				t.Errorf("temporary zips left behind: %v", zips)
+			}
+
+			cdn.Clear(fakecdn.ZipPath("2016"))
+			if err := in.Sync(versionsDir, clients, false); err != nil {
+				t.Fatalf("Sync after the fault cleared: %v", err)
+			}
+		})
+	}
+}
+
+func TestManifestFailure(t *testing.T) {
+	cdn := newCDN(t)
+	cdn.Fail(fakecdn.ManifestPath, fakecdn.Fault{Status: http.StatusServiceUnavailable})
+
+	in, _ := newInstaller(download.HTTPSource{}, progress.InstallStages)
+	if _, err := in.Manifest(cdn.ManifestURL()); err == nil {
+		t.Fatal("Manifest succeeded against a failing server")
+	}
+}
+
+func TestManifestRejectsBadYears(t *testing.T) {
+	cdn := newCDN(t)
+	in, _ := newInstaller(download.HTTPSource{}, progress.InstallStages)
+	clients := fetch(t, in, cdn)
+
+	// The year becomes part of the client directory's name.
+	cdn.SetClient("2016/../../x", clients["2016"])
+	if _, err := in.Manifest(cdn.ManifestURL()); err == nil || !strings.Contains(err.Error(), "invalid client year") {
+		t.Errorf("Manifest error = %v, want the year rejected", err)
+	}
+}
+
+func TestVerifyRejectsEscapingPaths(t *testing.T) {
+	cdn := newCDN(t)
+	root := t.TempDir()
+	versionsDir := filepath.Join(root, "Versions")
+	clientDir := install.ClientDir(versionsDir, "2016")
+	outside := filepath.Join(versionsDir, "escape")
+
+	in, _ := newInstaller(download.HTTPSource{}, progress.ClientStages)
+	clients := fetch(t, in, cdn)
+	if err := in.Sync(versionsDir, clients, false); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	// The CDN really serves the file, so only the path check keeps a repair
+	// from writing it next to the client directory.
+	payload := []byte("escaped")
+	sum := sha1.Sum(payload)
+	cdn.Put(fakecdn.FilePath("2016", "../escape"), payload)
+	client := clients["2016"]
+	client.Files = append(append([]manifest.File(nil), client.Files...), manifest.File{Path: "../escape", SHA1: hex.EncodeToString(sum[:]), Size: int64(len(payload))})
+	cdn.SetClient("2016", client)
+	clients = fetch(t, in, cdn)
+
+	for _, repair := range []bool{false, true} {
+		_, err := in.VerifyAll(versionsDir, clients, "2016", repair)
+		if err == nil || !strings.Contains(err.Error(), "escapes the client directory") {
+			t.Errorf("VerifyAll(repair=%v) error = %v, want the bad path rejected", repair, err)
+		}
+	}
+	if err := in.Repair(clientDir, "2016", clients["2016"], &install.VerifyResult{Year: "2016", Missing: []string{"../escape"}}); err == nil {
+		t.Error("Repair accepted a path outside the client directory")
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Errorf("file written outside the client directory: %v", err)
+	}
+}
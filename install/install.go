@@ -0,0 +1,369 @@
+package install
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sylicitybootstrapper/download"
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/manifest"
+	"sylicitybootstrapper/progress"
+)
+
+const ExeName = "SylicityPlayerBeta.exe"
+
+// Installer downloads, installs, verifies and repairs clients. Source and FS
+// default to the network and the real filesystem, DownloadDir to
+// DefaultDownloadDir; Tracker may be nil.
+type Installer struct {
+	Source      download.Source
+	FS          FS
+	Tracker     *progress.Tracker
+	Concurrency int
+	DownloadDir string
+}
+
+func (in *Installer) source() download.Source {
+	if in.Source == nil {
+		return download.Default
+	}
+	return in.Source
+}
+
+func (in *Installer) fs() FS {
+	if in.FS == nil {
+		return OS
+	}
+	return in.FS
+}
+
+func ClientDir(versionsDir, year string) string {
+	return filepath.Join(versionsDir, fmt.Sprintf("Client%s", year))
+}
+
+// LogDir holds the output of a supervised client. Verify leaves it alone.
+func LogDir(clientDir string) string {
+	return filepath.Join(clientDir, "logs")
+}
+
+// Installed reports whether the client in dir has its executable.
+func Installed(fsys FS, clientDir string) bool {
+	_, err := fsys.Stat(filepath.Join(clientDir, ExeName))
+	return err == nil
+}
+
+func ReadManifest(fsys FS, clientDir string) (*manifest.Installed, error) {
+	f, err := fsys.Open(filepath.Join(clientDir, manifest.InstalledName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return manifest.DecodeInstalled(f)
+}
+
+func WriteManifest(fsys FS, clientDir string, installed manifest.Installed) error {
+	f, err := fsys.OpenFile(filepath.Join(clientDir, manifest.InstalledName), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	err = manifest.EncodeInstalled(f, installed)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Manifest fetches the client list at url as the manifest stage.
+func (in *Installer) Manifest(url string) (map[string]manifest.Client, error) {
+	in.Tracker.Report(progress.Update{Stage: progress.StageManifest, Fraction: 0, Message: l10n.T("install.fetchingList")})
+	body, _, err := in.source().Open(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	clients, err := manifest.Decode(body)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("fetched client manifest", "url", url, "clients", len(clients))
+	in.Tracker.Report(progress.Update{Stage: progress.StageManifest, Fraction: 1, Message: l10n.T("install.fetchedList")})
+	return clients, nil
+}
+
+// Sync installs every client in clients that is missing from versionsDir,
+// or all of them with force set.
+func (in *Installer) Sync(versionsDir string, clients map[string]manifest.Client, force bool) error {
+	fsys := in.fs()
+	if err := fsys.MkdirAll(versionsDir, 0755); err != nil {
+		return err
+	}
+
+	var years []string
+	for year := range clients {
+		if !force && Installed(fsys, ClientDir(versionsDir, year)) {
+			slog.Info("client already installed, skipping", "year", year)
+			continue
+		}
+		years = append(years, year)
+	}
+	sort.Strings(years)
+	in.Tracker.SetItems(years)
+
+	sem := make(chan struct{}, max(in.Concurrency, 1))
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errs []error
+
+	for _, year := range years {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := in.Client(ClientDir(versionsDir, year), year, clients[year]); err != nil {
+				errMu.Lock()
+				errs = append(errs, err)
+				errMu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Client replaces whatever is in clientDir with a fresh copy of the client.
+func (in *Installer) Client(clientDir, year string, info manifest.Client) error {
+	fsys := in.fs()
+	slog.Info("installing client", "year", year, "version", info.Version, "dir", clientDir)
+	lock, err := in.lockClient(clientDir, year, progress.StageDownload, filelock.Exclusive)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	in.Tracker.Message(progress.StageDownload, year, l10n.T("install.installing", "Year", year))
+
+	if err := fsys.RemoveAll(clientDir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := fsys.MkdirAll(clientDir, 0755); err != nil {
+		return err
+	}
+
+	zipPath, err := in.downloadVerifiedZip(year, info)
+	if err != nil {
+		return err
+	}
+	defer fsys.Remove(zipPath)
+
+	extractMsg := l10n.T("install.extracting", "Year", year)
+	files, err := in.unzip(zipPath, clientDir, nil, func(p float64) {
+		in.Tracker.Report(progress.Update{Stage: progress.StageExtract, Item: year, Fraction: p, Message: extractMsg})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract client %s: %w", year, err)
+	}
+
+	installed := manifest.Installed{Year: year, Version: info.Version, Hash: info.Hash, Files: files}
+	if err := WriteManifest(fsys, clientDir, installed); err != nil {
+		slog.Warn("could not write install manifest", "year", year, "err", err)
+	}
+	slog.Info("installed client", "year", year, "files", len(files))
+
+	in.Tracker.Report(progress.Update{Stage: progress.StageExtract, Item: year, Fraction: 1, Message: l10n.T("install.installed", "Year", year)})
+	return nil
+}
+
+// DefaultDownloadDir holds client zips while they are downloaded and
+// extracted. It is per user and only the bootstrapper writes to it, so it is
+// safe to remove on uninstall.
+func DefaultDownloadDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "Sylicity", "downloads"), nil
+}
+
+func (in *Installer) downloadDir() (string, error) {
+	if in.DownloadDir == "" {
+		return DefaultDownloadDir()
+	}
+	return in.DownloadDir, nil
+}
+
+// downloadVerifiedZip downloads the client zip to a temporary file and checks
+// it against the manifest hash. The caller removes the returned file.
+func (in *Installer) downloadVerifiedZip(year string, info manifest.Client) (string, error) {
+	fsys := in.fs()
+	dir, err := in.downloadDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the download folder: %w", err)
+	}
+	if err := fsys.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	out, err := fsys.CreateTemp(dir, "client-*.zip")
+	if err != nil {
+		return "", err
+	}
+	zipPath := out.Name()
+	started := time.Now()
+
+	err = download.Fetch(in.source(), info.URL, out, func(tp download.TransferProgress) {
+		in.Tracker.Report(progress.Update{
+			Stage:      progress.StageDownload,
+			Item:       year,
+			Fraction:   float64(tp.Fraction()),
+			Message:    l10n.T("install.downloading", "Year", year, "Progress", tp),
+			BytesDone:  tp.Done,
+			BytesTotal: tp.Total,
+			Rate:       tp.Rate,
+			ETA:        tp.ETA,
+		})
+	})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fsys.Remove(zipPath)
+		slog.Error("client download failed", "year", year, "url", info.URL, "err", err)
+		return "", fmt.Errorf("failed to download client %s: %w", year, err)
+	}
+	slog.Info("downloaded client", "year", year, "url", info.URL, "took", time.Since(started).Round(time.Millisecond))
+	in.Tracker.Report(progress.Update{Stage: progress.StageDownload, Item: year, Fraction: 1, Message: l10n.T("install.downloaded", "Year", year)})
+
+	verifyMsg := l10n.T("install.checkingDownload", "Year", year)
+	in.Tracker.Report(progress.Update{Stage: progress.StageVerify, Item: year, Fraction: 0, Message: verifyMsg})
+	if info.Hash != "" {
+		hash, err := hashFile(fsys, zipPath)
+		if err != nil {
+			fsys.Remove(zipPath)
+			return "", err
+		}
+		if !strings.EqualFold(hash, info.Hash) {
+			fsys.Remove(zipPath)
+			slog.Error("client download hash mismatch", "year", year, "expected", info.Hash, "got", hash)
+			return "", fmt.Errorf("client %s download is corrupt: expected hash %s, got %s", year, info.Hash, hash)
+		}
+	}
+	in.Tracker.Report(progress.Update{Stage: progress.StageVerify, Item: year, Fraction: 1, Message: verifyMsg})
+	return zipPath, nil
+}
+
+// unzip extracts zipPath into dest and returns the hashes of the written
+// files. When only is non-nil, entries not listed in it are skipped.
+func (in *Installer) unzip(zipPath, dest string, only map[string]bool, onProgress func(float64)) ([]manifest.File, error) {
+	fsys := in.fs()
+	zf, err := fsys.Open(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zf.Close()
+	stat, err := zf.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := zip.NewReader(zf, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	var totalBytes, doneBytes uint64
+	for _, f := range r.File {
+		totalBytes += f.UncompressedSize64
+	}
+
+	var files []manifest.File
+	for _, f := range r.File {
+		if totalBytes > 0 {
+			onProgress(float64(doneBytes) / float64(totalBytes))
+		}
+		doneBytes += f.UncompressedSize64
+
+		name, err := cleanRelPath(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("bad zip entry: %w", err)
+		}
+		fpath := filepath.Join(dest, filepath.FromSlash(name))
+		if f.FileInfo().IsDir() {
+			if only == nil {
+				fsys.MkdirAll(fpath, os.ModePerm)
+			}
+			continue
+		}
+		if only != nil && !only[name] {
+			continue
+		}
+		if err := fsys.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+			return nil, err
+		}
+		outFile, err := fsys.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+		if err != nil {
+			return nil, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			outFile.Close()
+			return nil, err
+		}
+		hash := sha1.New()
+		size, err := io.Copy(io.MultiWriter(outFile, hash), rc)
+		outFile.Close()
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, manifest.File{Path: name, SHA1: hex.EncodeToString(hash.Sum(nil)), Size: size})
+	}
+	return files, nil
+}
+
+func hashFile(fsys FS, path string) (string, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cleanRelPath cleans a slash-separated name from a zip or manifest and
+// rejects names that would land outside the client directory.
+func cleanRelPath(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) || filepath.VolumeName(filepath.FromSlash(clean)) != "" {
+		return "", fmt.Errorf("%q escapes the client directory", name)
+	}
+	return clean, nil
+}
+
+// checkFilePaths rejects a whole file list if any of its paths is unsafe.
+func checkFilePaths(files []manifest.File) error {
+	for _, file := range files {
+		if _, err := cleanRelPath(file.Path); err != nil {
+			return fmt.Errorf("bad file list: %w", err)
+		}
+	}
+	return nil
+}
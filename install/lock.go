@@ -0,0 +1,50 @@
+package install
+
+import (
+	"errors"
+	"log/slog"
+
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/progress"
+)
+
+// WaitingMessage is shown while another process holds a client's lock.
+func WaitingMessage() string {
+	return l10n.T("install.waiting")
+}
+
+// LockPath is the lock file for clientDir. It sits next to the directory, so
+// it outlives a reinstall.
+func LockPath(clientDir string) string {
+	return clientDir + ".lock"
+}
+
+// LockClient takes the advisory lock for clientDir: exclusive to change the
+// client, shared to read or launch it. onWait, if set, is called before
+// waiting on another process.
+func LockClient(clientDir string, mode filelock.Mode, onWait func()) (*filelock.Lock, error) {
+	path := LockPath(clientDir)
+	lock, err := filelock.TryAcquire(path, mode)
+	if !errors.Is(err, filelock.ErrLocked) {
+		return lock, err
+	}
+
+	slog.Info("waiting for client lock", "dir", clientDir, "exclusive", mode == filelock.Exclusive)
+	if onWait != nil {
+		onWait()
+	}
+	lock, err = filelock.Acquire(path, mode)
+	if err == nil {
+		slog.Info("got client lock", "dir", clientDir)
+	}
+	return lock, err
+}
+
+// lockClient reports the wait on stage as indeterminate, so front ends keep
+// animating.
+func (in *Installer) lockClient(clientDir, year string, stage progress.Stage, mode filelock.Mode) (*filelock.Lock, error) {
+	return LockClient(clientDir, mode, func() {
+		in.Tracker.Report(progress.Update{Stage: stage, Item: year, Fraction: -1, Message: WaitingMessage()})
+	})
+}
@@ -0,0 +1,59 @@
+package install_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+)
+
+func TestLockClient(t *testing.T) {
+	clientDir := install.ClientDir(t.TempDir(), "2016")
+
+	// Launches share the client.
+	first, err := install.LockClient(clientDir, filelock.Shared, func() { t.Error("shared lock waited for a shared one") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := install.LockClient(clientDir, filelock.Shared, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		lock, err := install.LockClient(clientDir, filelock.Exclusive, func() { close(waited) })
+		if err == nil {
+			err = lock.Release()
+		}
+		done <- err
+	}()
+
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("exclusive lock did not wait for the shared ones")
+	}
+	first.Release()
+	select {
+	case err := <-done:
+		t.Fatalf("exclusive lock taken while a shared one was held: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	second.Release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock file sits next to the client, so removing the client keeps it.
+	if err := os.RemoveAll(clientDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(install.LockPath(clientDir)); err != nil || filepath.Dir(install.LockPath(clientDir)) != filepath.Dir(clientDir) {
+		t.Errorf("lock file %s not next to the client: %v", install.LockPath(clientDir), err)
+	}
+}
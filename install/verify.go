@@ -0,0 +1,306 @@
+package install
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sylicitybootstrapper/download"
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/manifest"
+	"sylicitybootstrapper/progress"
+)
+
+type VerifyResult struct {
+	Year     string   `json:"year"`
+	Missing  []string `json:"missing"`
+	Modified []string `json:"modified"`
+	Extra    []string `json:"extra"`
+	Checked  int      `json:"checked"`
+}
+
+func (r *VerifyResult) OK() bool {
+	return len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+func (r *VerifyResult) Summary() string {
+	if r.OK() && len(r.Extra) == 0 {
+		return l10n.N("verify.allOK", r.Checked, "Year", r.Year)
+	}
+	return l10n.T("verify.problems", "Year", r.Year, "Missing", len(r.Missing), "Modified", len(r.Modified), "Extra", len(r.Extra))
+}
+
+func (in *Installer) expectedFiles(clientDir string, info manifest.Client) ([]manifest.File, error) {
+	files := info.Files
+	if len(files) == 0 {
+		installed, err := ReadManifest(in.fs(), clientDir)
+		if err != nil {
+			return nil, fmt.Errorf("no file list available (reinstall the client once to enable verification): %w", err)
+		}
+		files = installed.Files
+	}
+	if err := checkFilePaths(files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (in *Installer) Verify(clientDir, year string, info manifest.Client) (*VerifyResult, error) {
+	fsys := in.fs()
+	lock, err := in.lockClient(clientDir, year, progress.StageVerify, filelock.Shared)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	expected, err := in.expectedFiles(clientDir, info)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Year: year}
+	msg := l10n.T("install.verifying", "Year", year)
+
+	var totalBytes, doneBytes int64
+	for _, file := range expected {
+		totalBytes += file.Size
+	}
+	report := func(fraction float64) {
+		in.Tracker.Report(progress.Update{Stage: progress.StageVerify, Item: year, Fraction: fraction, Message: msg})
+	}
+	report(0)
+
+	known := map[string]bool{manifest.InstalledName: true}
+	for _, file := range expected {
+		known[file.Path] = true
+		fpath := filepath.Join(clientDir, filepath.FromSlash(file.Path))
+
+		hash, err := hashFile(fsys, fpath)
+		switch {
+		case os.IsNotExist(err):
+			result.Missing = append(result.Missing, file.Path)
+		case err != nil:
+			return nil, err
+		case !strings.EqualFold(hash, file.SHA1):
+			result.Modified = append(result.Modified, file.Path)
+		}
+		result.Checked++
+
+		doneBytes += file.Size
+		if totalBytes > 0 {
+			report(float64(doneBytes) / float64(totalBytes))
+		} else {
+			report(float64(result.Checked) / float64(len(expected)))
+		}
+	}
+
+	// The client's lock file sits next to clientDir, so only the logs need
+	// skipping.
+	err = fsys.WalkDir(clientDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == LogDir(clientDir) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(clientDir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); !known[rel] {
+			result.Extra = append(result.Extra, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(result.Extra)
+	slog.Info("verified client", "year", year, "checked", result.Checked, "missing", len(result.Missing), "modified", len(result.Modified), "extra", len(result.Extra))
+	report(1)
+	return result, nil
+}
+
+// Repair re-fetches the missing and modified files from result. Extra files
+// are left alone, as they may be user content.
+func (in *Installer) Repair(clientDir, year string, info manifest.Client, result *VerifyResult) error {
+	fsys := in.fs()
+	broken := append(append([]string(nil), result.Missing...), result.Modified...)
+	if len(broken) == 0 {
+		return nil
+	}
+	// result normally comes from Verify, which has checked these already.
+	if err := checkFilePaths(info.Files); err != nil {
+		return err
+	}
+	for _, name := range broken {
+		if _, err := cleanRelPath(name); err != nil {
+			return err
+		}
+	}
+	lock, err := in.lockClient(clientDir, year, progress.StageDownload, filelock.Exclusive)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	msg := l10n.T("install.repairing", "Year", year)
+	slog.Info("repairing client", "year", year, "files", broken, "perFile", info.FilesURL != "" && len(info.Files) > 0)
+	in.Tracker.Report(progress.Update{Stage: progress.StageDownload, Item: year, Fraction: 0, Message: msg})
+
+	if info.FilesURL != "" && len(info.Files) > 0 {
+		hashes := map[string]string{}
+		for _, file := range info.Files {
+			hashes[file.Path] = file.SHA1
+		}
+		for i, name := range broken {
+			if err := in.downloadFile(info.FilesURL, name, hashes[name], clientDir); err != nil {
+				return fmt.Errorf("failed to repair %s: %w", name, err)
+			}
+			in.Tracker.Report(progress.Update{Stage: progress.StageDownload, Item: year, Fraction: float64(i+1) / float64(len(broken)), Message: msg})
+		}
+		in.Tracker.Report(progress.Update{Stage: progress.StageExtract, Item: year, Fraction: 1, Message: msg})
+		return nil
+	}
+
+	zipPath, err := in.downloadVerifiedZip(year, info)
+	if err != nil {
+		return err
+	}
+	defer fsys.Remove(zipPath)
+
+	only := map[string]bool{}
+	for _, name := range broken {
+		only[name] = true
+	}
+	_, err = in.unzip(zipPath, clientDir, only, func(p float64) {
+		in.Tracker.Report(progress.Update{Stage: progress.StageExtract, Item: year, Fraction: p, Message: msg})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract client %s: %w", year, err)
+	}
+
+	if _, err := ReadManifest(fsys, clientDir); err != nil {
+		files, err := in.hashInstalledFiles(clientDir, info.Files)
+		if err == nil {
+			WriteManifest(fsys, clientDir, manifest.Installed{Year: year, Version: info.Version, Hash: info.Hash, Files: files})
+		}
+	}
+
+	in.Tracker.Report(progress.Update{Stage: progress.StageExtract, Item: year, Fraction: 1, Message: msg})
+	return nil
+}
+
+func (in *Installer) hashInstalledFiles(clientDir string, files []manifest.File) ([]manifest.File, error) {
+	var hashed []manifest.File
+	for _, file := range files {
+		hash, err := hashFile(in.fs(), filepath.Join(clientDir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return nil, err
+		}
+		hashed = append(hashed, manifest.File{Path: file.Path, SHA1: hash, Size: file.Size})
+	}
+	return hashed, nil
+}
+
+func (in *Installer) downloadFile(baseURL, name, wantHash, clientDir string) error {
+	fsys := in.fs()
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	fileURL := strings.TrimSuffix(baseURL, "/") + "/" + strings.Join(segments, "/")
+
+	fpath := filepath.Join(clientDir, filepath.FromSlash(name))
+	if err := fsys.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := fsys.CreateTemp(filepath.Dir(fpath), ".repair-*")
+	if err != nil {
+		return err
+	}
+	err = download.Fetch(in.source(), fileURL, tmp, nil)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fsys.Remove(tmp.Name())
+		return err
+	}
+
+	if wantHash != "" {
+		hash, err := hashFile(fsys, tmp.Name())
+		if err != nil || !strings.EqualFold(hash, wantHash) {
+			fsys.Remove(tmp.Name())
+			return fmt.Errorf("downloaded file does not match its hash")
+		}
+	}
+	return fsys.Rename(tmp.Name(), fpath)
+}
+
+// InstalledYears lists the ClientYYYY folders in versionsDir.
+func (in *Installer) InstalledYears(versionsDir string) []string {
+	entries, err := in.fs().ReadDir(versionsDir)
+	if err != nil {
+		return nil
+	}
+	var years []string
+	for _, entry := range entries {
+		if year, ok := strings.CutPrefix(entry.Name(), "Client"); ok && entry.IsDir() && year != "" {
+			years = append(years, year)
+		}
+	}
+	sort.Strings(years)
+	return years
+}
+
+// VerifyAll verifies, and with repair set also repairs, the clients
+// installed in versionsDir. An empty year means every installed client.
+func (in *Installer) VerifyAll(versionsDir string, clients map[string]manifest.Client, year string, repair bool) ([]*VerifyResult, error) {
+	var years []string
+	for _, installed := range in.InstalledYears(versionsDir) {
+		if year == "" || installed == year {
+			years = append(years, installed)
+		}
+	}
+	in.Tracker.SetItems(years)
+
+	var results []*VerifyResult
+	for _, year := range years {
+		clientDir := ClientDir(versionsDir, year)
+		info, ok := clients[year]
+		if !ok {
+			slog.Warn("installed client is no longer in the manifest, skipping", "year", year)
+			continue
+		}
+
+		result, err := in.Verify(clientDir, year, info)
+		if err != nil {
+			return results, fmt.Errorf("failed to verify client %s: %w", year, err)
+		}
+		if repair && !result.OK() {
+			if err := in.Repair(clientDir, year, info, result); err != nil {
+				return results, err
+			}
+			if result, err = in.Verify(clientDir, year, info); err != nil {
+				return results, fmt.Errorf("failed to verify client %s: %w", year, err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if year != "" && len(results) == 0 {
+		return nil, fmt.Errorf("client %s is not installed", year)
+	}
+	return results, nil
+}
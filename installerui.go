@@ -0,0 +1,248 @@
+package main
+
+import (
+	"time"
+
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/launch"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const loaderChunkWidth = 80
+
+// installerView owns the widgets of the installer window. All of its methods
+// must run on the UI goroutine; background work reaches it through run.
+type installerView struct {
+	label  *widget.Label
+	loader fyne.CanvasObject
+	track  *canvas.Rectangle
+	chunk  *canvas.Rectangle
+	width  float32
+	win    fyne.Window
+	// content is the window content the widgets live in.
+	content fyne.CanvasObject
+
+	button       *widget.Button
+	manageButton *widget.Button
+	// diagnosticsButton is offered when a run fails.
+	diagnosticsButton *widget.Button
+
+	// onRepair is offered as the finish action when a run ends Repairable,
+	// and with onRetry when a supervised client crashes.
+	onRepair func()
+	onRetry  func()
+	// queue holds forwarded requests; they start when a run ends.
+	queue *requestQueue
+
+	running    bool
+	closeTimer *time.Timer
+
+	animation *fyne.Animation
+	// pulse fades a full bar in and out in place of animation when the
+	// system asks for reduced motion.
+	pulse         *fyne.Animation
+	reducedMotion bool
+	animating     bool
+}
+
+func newInstallerView(label *widget.Label, loader fyne.CanvasObject, track, chunk *canvas.Rectangle, width float32, button, manageButton, diagnosticsButton *widget.Button, win fyne.Window, content fyne.CanvasObject) *installerView {
+	v := &installerView{
+		label:   label,
+		loader:  loader,
+		track:   track,
+		chunk:   chunk,
+		width:   width,
+		win:     win,
+		content: content,
+
+		button:            button,
+		manageButton:      manageButton,
+		diagnosticsButton: diagnosticsButton,
+	}
+	v.animation = fyne.NewAnimation(2*time.Second, func(p float32) {
+		v.chunk.Move(fyne.NewPos(-loaderChunkWidth+p*(v.width+loaderChunkWidth), 0))
+	})
+	v.animation.Curve = fyne.AnimationLinear
+	v.animation.RepeatCount = fyne.AnimationRepeatForever
+	v.pulse = fyne.NewAnimation(1500*time.Millisecond, func(p float32) {
+		v.chunk.FillColor = withAlpha(theme.PrimaryColor(), uint8(0x40+p*0xbf))
+		v.chunk.Refresh()
+	})
+	v.pulse.AutoReverse = true
+	v.pulse.RepeatCount = fyne.AnimationRepeatForever
+	return v
+}
+
+// run starts logic on its own goroutine and applies every event it sends,
+// in order, on the UI goroutine.
+func (v *installerView) run(logic func(events chan<- installerEvent)) {
+	v.reset()
+
+	events := make(chan installerEvent, 16)
+	go logic(events)
+	go func() {
+		for event := range events {
+			fyne.Do(func() { v.apply(event) })
+		}
+	}()
+}
+
+func (v *installerView) reset() {
+	v.running = true
+	if v.closeTimer != nil {
+		v.closeTimer.Stop()
+	}
+	v.loader.Show()
+	v.track.Move(fyne.NewPos(0, 0))
+	v.track.Resize(fyne.NewSize(v.width, 8))
+	v.button.SetText(l10n.T("common.cancel"))
+	v.button.OnTapped = func() { v.win.Close() }
+	v.manageButton.Hide()
+	v.diagnosticsButton.Hide()
+	v.startAnimation()
+}
+
+func (v *installerView) apply(event installerEvent) {
+	switch event.Kind {
+	case eventStatus:
+		v.label.SetText(event.Message)
+		v.startAnimation()
+	case eventProgress:
+		v.label.SetText(event.Progress.Message)
+		// Indeterminate snapshots come from downloads without
+		// Content-Length; keep the animation running for those.
+		if event.Progress.Indeterminate {
+			v.startAnimation()
+		} else {
+			v.setProgress(float32(max(0, min(event.Progress.Overall, 1))))
+		}
+	case eventFailed:
+		v.finish(event.Message, l10n.T("common.close"), nil)
+		v.diagnosticsButton.Show()
+	case eventDone:
+		if event.Repairable && v.onRepair != nil {
+			v.finish(event.Message, l10n.T("common.repair"), v.onRepair)
+		} else {
+			v.finish(event.Message, l10n.T("installer.finish"), nil)
+		}
+		v.manageButton.Show()
+	case eventCrashed:
+		v.finish(event.Message, l10n.T("common.close"), nil)
+		v.diagnosticsButton.Show()
+		showCrashDialog(event.Crash, v.onRetry, v.onRepair, v.win)
+	case eventLaunched:
+		v.running = false
+		if !v.queue.next() {
+			v.closeTimer = time.AfterFunc(5*time.Second, func() { fyne.Do(v.closeOrNext) })
+		}
+	}
+}
+
+// closeOrNext moves on to the next forwarded request, or closes the window
+// when none is waiting.
+func (v *installerView) closeOrNext() {
+	if !v.queue.next() {
+		v.win.Close()
+	}
+}
+
+func (v *installerView) startAnimation() {
+	if v.animating {
+		return
+	}
+	v.animating = true
+	if v.reducedMotion {
+		v.chunk.Move(fyne.NewPos(0, 0))
+		v.chunk.Resize(fyne.NewSize(v.width, 8))
+		v.pulse.Start()
+		return
+	}
+	v.chunk.Resize(fyne.NewSize(loaderChunkWidth, 8))
+	v.animation.Start()
+}
+
+func (v *installerView) stopAnimation() {
+	if !v.animating {
+		return
+	}
+	v.animating = false
+	if v.reducedMotion {
+		v.pulse.Stop()
+		v.chunk.FillColor = theme.PrimaryColor()
+		v.chunk.Refresh()
+		return
+	}
+	v.animation.Stop()
+}
+
+func (v *installerView) setProgress(fraction float32) {
+	v.stopAnimation()
+	v.chunk.Move(fyne.NewPos(0, 0))
+	v.chunk.Resize(fyne.NewSize(v.width*fraction, 8))
+}
+
+// finish shows message and a single button. A nil onTapped closes the
+// window, or moves on to the next forwarded request if one is waiting.
+func (v *installerView) finish(message, buttonText string, onTapped func()) {
+	v.running = false
+	v.stopAnimation()
+	v.label.SetText(message)
+	v.loader.Hide()
+	if onTapped == nil {
+		onTapped = v.closeOrNext
+		if n := v.queue.waiting(); n > 0 {
+			buttonText = l10n.N("installer.continue", n)
+		}
+	}
+	v.button.SetText(buttonText)
+	v.button.OnTapped = onTapped
+}
+
+// showCrashDialog reports a client that quit on start-up and offers to try
+// again or repair it. Either action may be nil.
+func showCrashDialog(exit *launch.Exit, onRetry, onRepair func(), win fyne.Window) {
+	win.SetFixedSize(false)
+	if size := win.Canvas().Size(); size.Width < 560 || size.Height < 420 {
+		win.Resize(fyne.NewSize(max(size.Width, 560), max(size.Height, 420)))
+	}
+
+	summary := widget.NewLabel(l10n.T("crash.summary", "Code", exit.Code, "After", exit.After.Round(time.Second/10)))
+	summary.Wrapping = fyne.TextWrapWord
+	excerpt := exit.Excerpt
+	if excerpt == "" {
+		excerpt = l10n.T("crash.noOutput")
+	}
+	output := widget.NewLabelWithStyle(excerpt, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	scroll := container.NewScroll(output)
+	scroll.SetMinSize(fyne.NewSize(480, 160))
+
+	content := container.NewBorder(summary, nil, nil, nil, scroll)
+	if exit.LogFile != "" {
+		content = container.NewBorder(summary, widget.NewLabel(l10n.T("crash.fullOutput", "Path", exit.LogFile)), nil, nil, scroll)
+	}
+
+	d := dialog.NewCustomWithoutButtons(l10n.T("crash.title"), content, win)
+	buttons := []fyne.CanvasObject{widget.NewButton(l10n.T("common.close"), d.Hide)}
+	if onRepair != nil {
+		buttons = append(buttons, widget.NewButton(l10n.T("common.repair"), func() {
+			d.Hide()
+			onRepair()
+		}))
+	}
+	if onRetry != nil {
+		retry := widget.NewButton(l10n.T("crash.retry"), func() {
+			d.Hide()
+			onRetry()
+		})
+		retry.Importance = widget.HighImportance
+		buttons = append(buttons, retry)
+	}
+	d.SetButtons(buttons)
+	d.Show()
+}
@@ -0,0 +1,147 @@
+// Package instance keeps a single bootstrapper running per app directory.
+// Later invocations hand their request to it over a local socket instead of
+// installing into the same directory at the same time.
+package instance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sylicitybootstrapper/filelock"
+)
+
+const (
+	lockName   = "sylicity.lock"
+	socketName = "sylicity.sock"
+
+	// ack is the primary's reply once a request was accepted.
+	ack = "ok\n"
+)
+
+var (
+	// ErrRunning is returned by Acquire when another process is the primary.
+	ErrRunning = errors.New("another bootstrapper is already running")
+	// ErrRefused is returned by Forward when the primary turned the request
+	// down.
+	ErrRefused = errors.New("the running bootstrapper refused the request")
+)
+
+// Primary is held by the first bootstrapper started for an app directory.
+type Primary struct {
+	lock     *filelock.Lock
+	listener net.Listener
+}
+
+// Acquire makes this process the primary for dir. It returns ErrRunning if
+// another process already is; Forward reaches that one.
+func Acquire(dir string) (*Primary, error) {
+	lock, err := filelock.TryAcquire(filepath.Join(dir, lockName), filelock.Exclusive)
+	if errors.Is(err, filelock.ErrLocked) {
+		return nil, ErrRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A socket left behind by a crashed primary would make Listen fail. We
+	// hold the lock, so nobody else is using it.
+	path := filepath.Join(dir, socketName)
+	os.Remove(path)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		lock.Release()
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	return &Primary{lock: lock, listener: listener}, nil
+}
+
+// Serve passes every forwarded request to handle, one at a time, until Close
+// is called. An error from handle is sent back to the forwarding process.
+func (p *Primary) Serve(handle func(request json.RawMessage) error) {
+	for {
+		conn, err := p.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("failed to accept forwarded request", "err", err)
+			continue
+		}
+		p.receive(conn, handle)
+	}
+}
+
+func (p *Primary) receive(conn net.Conn, handle func(json.RawMessage) error) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		slog.Warn("failed to read forwarded request", "err", err)
+		return
+	}
+	request := json.RawMessage(line)
+	if !json.Valid(request) {
+		slog.Warn("ignoring malformed forwarded request")
+		return
+	}
+	if err := handle(request); err != nil {
+		conn.Write([]byte(strings.ReplaceAll(err.Error(), "\n", " ") + "\n"))
+		return
+	}
+	conn.Write([]byte(ack))
+}
+
+// Close stops serving and releases the app directory.
+func (p *Primary) Close() error {
+	err := p.listener.Close()
+	if lockErr := p.lock.Release(); err == nil {
+		err = lockErr
+	}
+	return err
+}
+
+// Forward sends request, encoded as JSON, to the primary for dir and waits
+// for it to be accepted.
+func Forward(dir string, request any) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	// The primary takes its lock before it listens, so it may need a moment.
+	path := filepath.Join(dir, socketName)
+	var conn net.Conn
+	for deadline := time.Now().Add(3 * time.Second); ; {
+		conn, err = net.DialTimeout("unix", path, time.Second)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reach the running bootstrapper: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("failed to hear back from the running bootstrapper: %w", err)
+	}
+	if reply != ack {
+		return fmt.Errorf("%w: %s", ErrRefused, strings.TrimSpace(reply))
+	}
+	return nil
+}
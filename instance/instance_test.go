@@ -0,0 +1,57 @@
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestForwardToPrimary(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := Acquire(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan json.RawMessage, 1)
+	go primary.Serve(func(request json.RawMessage) error {
+		var r struct{ LaunchMode string }
+		json.Unmarshal(request, &r)
+		if r.LaunchMode == "bogus" {
+			return errors.New("unknown mode\nbogus")
+		}
+		received <- request
+		return nil
+	})
+
+	if _, err := Acquire(dir); !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Acquire = %v, want ErrRunning", err)
+	}
+
+	type request struct{ LaunchMode, ClientYear string }
+	if err := Forward(dir, request{"play", "2016"}); err != nil {
+		t.Fatal(err)
+	}
+	var got request
+	if err := json.Unmarshal(<-received, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (request{"play", "2016"}) {
+		t.Errorf("primary received %+v", got)
+	}
+
+	err = Forward(dir, request{"bogus", ""})
+	if !errors.Is(err, ErrRefused) || !strings.Contains(err.Error(), "unknown mode bogus") {
+		t.Errorf("Forward = %v, want the primary's error", err)
+	}
+
+	if err := primary.Close(); err != nil {
+		t.Fatal(err)
+	}
+	next, err := Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire after Close: %v", err)
+	}
+	next.Close()
+}
@@ -0,0 +1,115 @@
+// Package l10n translates the bootstrapper's user-facing text. Catalogs live
+// in catalogs/<language>.json; English is the reference every other catalog
+// must match, and the fallback for anything missing.
+package l10n
+
+import (
+	"embed"
+	"encoding/json"
+	"log/slog"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/jeandeaual/go-locale"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+)
+
+//go:embed catalogs/*.json
+var catalogs embed.FS
+
+var (
+	bundle = newBundle()
+
+	mu        sync.RWMutex
+	localizer = i18n.NewLocalizer(bundle, language.English.String())
+)
+
+func newBundle() *i18n.Bundle {
+	b := i18n.NewBundle(language.English)
+	b.RegisterUnmarshalFunc("json", json.Unmarshal)
+	entries, _ := catalogs.ReadDir("catalogs")
+	for _, entry := range entries {
+		if _, err := b.LoadMessageFileFS(catalogs, path.Join("catalogs", entry.Name())); err != nil {
+			panic(err)
+		}
+	}
+	return b
+}
+
+// Setup picks the language to translate into: lang if set, e.g. "de" or
+// "pt-BR", then the OS locales, then English.
+func Setup(lang string) {
+	var langs []string
+	if lang != "" {
+		langs = append(langs, lang)
+	}
+	osLocales, err := locale.GetLocales()
+	if err != nil {
+		slog.Debug("could not read OS locales", "err", err)
+	}
+	langs = append(langs, osLocales...)
+
+	mu.Lock()
+	localizer = i18n.NewLocalizer(bundle, langs...)
+	mu.Unlock()
+	slog.Info("picked language", "language", Language(), "requested", langs)
+}
+
+// Language is the catalog in use, e.g. "en".
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, tag, _ := localizer.LocalizeWithTag(&i18n.LocalizeConfig{MessageID: "language.name"})
+	return tag.String()
+}
+
+// Languages lists the languages there are catalogs for.
+func Languages() []string {
+	var langs []string
+	for _, tag := range bundle.LanguageTags() {
+		langs = append(langs, tag.String())
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// T translates the message id. data fills its {{.Name}} placeholders and is
+// given as name/value pairs, e.g. T("install.extracting", "Year", year).
+func T(id string, data ...any) string {
+	return localize(&i18n.LocalizeConfig{MessageID: id, TemplateData: templateData(data)})
+}
+
+// N translates a message whose wording depends on count, which is also
+// available to it as {{.Count}}.
+func N(id string, count int, data ...any) string {
+	values := templateData(data)
+	values["Count"] = count
+	return localize(&i18n.LocalizeConfig{MessageID: id, PluralCount: count, TemplateData: values})
+}
+
+func localize(config *i18n.LocalizeConfig) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	// A message missing from the picked catalog comes back in English along
+	// with an error; only a message missing everywhere shows its id.
+	msg, err := localizer.Localize(config)
+	if err != nil {
+		slog.Debug("translation problem", "id", config.MessageID, "err", err)
+	}
+	if msg == "" {
+		return config.MessageID
+	}
+	return msg
+}
+
+func templateData(pairs []any) map[string]any {
+	data := make(map[string]any, len(pairs)/2+1)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if name, ok := pairs[i].(string); ok {
+			data[name] = pairs[i+1]
+		}
+	}
+	return data
+}
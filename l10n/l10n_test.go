@@ -0,0 +1,132 @@
+package l10n
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// pluralForms are the CLDR forms each catalog has to give plural messages.
+var pluralForms = map[string][]string{
+	"en":    {"one", "other"},
+	"de":    {"one", "other"},
+	"es":    {"one", "many", "other"},
+	"pt-BR": {"one", "many", "other"},
+	"ru":    {"one", "few", "many", "other"},
+}
+
+func readCatalog(t *testing.T, name string) map[string]any {
+	t.Helper()
+	data, err := catalogs.ReadFile("catalogs/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages map[string]any
+	if err := json.Unmarshal(data, &messages); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return messages
+}
+
+func TestCatalogsHaveEveryKey(t *testing.T) {
+	reference := readCatalog(t, "en.json")
+	entries, err := catalogs.ReadDir("catalogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		lang := strings.TrimSuffix(entry.Name(), ".json")
+		forms, ok := pluralForms[lang]
+		if !ok {
+			t.Errorf("%s: add the language's plural forms to pluralForms", entry.Name())
+			continue
+		}
+		messages := readCatalog(t, entry.Name())
+
+		for key, message := range reference {
+			translated, ok := messages[key]
+			if !ok {
+				t.Errorf("%s is missing %q", entry.Name(), key)
+				continue
+			}
+			if _, plural := message.(map[string]any); !plural {
+				continue
+			}
+			variants, ok := translated.(map[string]any)
+			if !ok {
+				t.Errorf("%s: %q must be plural, with %s", entry.Name(), key, strings.Join(forms, ", "))
+				continue
+			}
+			for _, form := range forms {
+				if _, ok := variants[form]; !ok {
+					t.Errorf("%s: %q is missing its %q form", entry.Name(), key, form)
+				}
+			}
+		}
+		for key := range messages {
+			if _, ok := reference[key]; !ok {
+				t.Errorf("%s has %q, which en.json does not", entry.Name(), key)
+			}
+		}
+	}
+}
+
+var callPattern = regexp.MustCompile(`l10n\.[TN]\("([^"]+)"`)
+
+func TestCodeUsesKnownKeys(t *testing.T) {
+	reference := readCatalog(t, "en.json")
+	var missing []string
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range callPattern.FindAllStringSubmatch(string(data), -1) {
+			if _, ok := reference[match[1]]; !ok && !slices.Contains(missing, match[1]) {
+				missing = append(missing, match[1])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		t.Errorf("en.json is missing %q, which the code uses", key)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Cleanup(func() { Setup("en") })
+
+	Setup("de")
+	if got, want := T("install.installing", "Year", "2016"), "Client 2016 wird installiert..."; got != want {
+		t.Errorf("T = %q, want %q", got, want)
+	}
+
+	Setup("ru")
+	for count, want := range map[int]string{
+		1:  "Клиент 2016: 1 файл в порядке",
+		3:  "Клиент 2016: все 3 файла в порядке",
+		25: "Клиент 2016: все 25 файлов в порядке",
+	} {
+		if got := N("verify.allOK", count, "Year", "2016"); got != want {
+			t.Errorf("N(%d) = %q, want %q", count, got, want)
+		}
+	}
+
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key = %q, want the key itself", got)
+	}
+}
@@ -0,0 +1,180 @@
+package launch
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"sylicitybootstrapper/clientlaunchcalls"
+	"sylicitybootstrapper/filelock"
+)
+
+type Options struct {
+	// Executable is the client binary, e.g. Versions/Client2016/SylicityPlayerBeta.exe.
+	Executable string
+
+	// Play joins a game with the ticket and join script below; otherwise
+	// the client opens on its own.
+	Play       bool
+	AuthURL    string
+	AuthTicket string
+	JoinScript string
+
+	ExtraArgs []string
+	// Wrapper, if set, is run instead with the executable and its
+	// arguments appended, e.g. ["wine"].
+	Wrapper []string
+	Env     map[string]string
+
+	// LogFile receives the client's output when it is supervised.
+	LogFile string
+
+	// Lock, if set, is the client's shared lock. Client and Supervise take it
+	// over and hold it until the client exits, so nobody updates or removes
+	// the client while it runs. On Windows it is only held for as long as the
+	// bootstrapper runs.
+	Lock *filelock.Lock
+}
+
+// Command is a fully resolved process to start. A nil Env inherits the
+// bootstrapper's environment.
+type Command struct {
+	Path string
+	Args []string
+	Env  []string
+	Lock *filelock.Lock
+}
+
+// String is the command line with the auth ticket blanked out, so it can be
+// logged.
+func (c Command) String() string {
+	args := append([]string{c.Path}, c.Args...)
+	for i := 1; i < len(args); i++ {
+		if args[i-1] == "--authenticationTicket" {
+			args[i] = "[redacted]"
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// Spawner starts a client process without waiting for it. ExecSpawner is the
+// real one; tests can record commands instead.
+type Spawner interface {
+	Spawn(cmd Command) error
+}
+
+func BuildCommand(opts Options) Command {
+	var args []string
+	if opts.Play {
+		args = append(args, "--play")
+		args = append(args, "--authenticationUrl", opts.AuthURL)
+		args = append(args, "--authenticationTicket", opts.AuthTicket)
+		args = append(args, "--joinScriptUrl", opts.JoinScript)
+	}
+	args = append(args, opts.ExtraArgs...)
+
+	cmd := Command{Path: opts.Executable, Args: args, Lock: opts.Lock}
+	if len(opts.Wrapper) > 0 {
+		cmd.Path = opts.Wrapper[0]
+		cmd.Args = append(append(append([]string{}, opts.Wrapper[1:]...), opts.Executable), args...)
+	}
+
+	if len(opts.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range opts.Env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	return cmd
+}
+
+func Client(spawner Spawner, opts Options) error {
+	if _, err := os.Stat(opts.Executable); os.IsNotExist(err) {
+		releaseLock(opts.Lock)
+		return fmt.Errorf("client executable not found: %s", opts.Executable)
+	}
+
+	cmd := BuildCommand(opts)
+	slog.Info("launching client", "command", cmd.String(), "env", len(opts.Env))
+	if err := spawner.Spawn(cmd); err != nil {
+		slog.Error("client launch failed", "err", err)
+		return err
+	}
+	return nil
+}
+
+// ExecSpawner starts the client detached from the bootstrapper, so it keeps
+// running after the installer window closes.
+type ExecSpawner struct{}
+
+func (ExecSpawner) Spawn(command Command) error {
+	cmd := exec.Command(command.Path, command.Args...)
+	clientlaunchcalls.SetupProcAttr(cmd)
+	cmd.Env = command.Env
+	lock := holdLock(cmd, command.Lock)
+
+	if err := cmd.Start(); err != nil {
+		lock.failed()
+		return fmt.Errorf("failed to start client: %w", err)
+	}
+	lock.started()
+
+	if cmd.Process == nil {
+		return fmt.Errorf("process started but process handle is nil")
+	}
+
+	if lock.waits() {
+		go func() {
+			cmd.Wait()
+			lock.exited()
+		}()
+		return nil
+	}
+	if err := cmd.Process.Release(); err != nil {
+		return fmt.Errorf("started client but failed to detach (release): %w", err)
+	}
+
+	return nil
+}
+
+// clientLock is a client's lock while the client starts and runs. Where the
+// OS allows it the client inherits the lock and our handle is closed once it
+// has started; otherwise we hold the lock until the client exits.
+type clientLock struct {
+	lock      *filelock.Lock
+	inherited bool
+}
+
+func holdLock(cmd *exec.Cmd, lock *filelock.Lock) *clientLock {
+	return &clientLock{lock: lock, inherited: lock != nil && lock.PassTo(cmd)}
+}
+
+// waits reports whether the lock has to be released by waiting for the
+// client.
+func (c *clientLock) waits() bool {
+	return c.lock != nil && !c.inherited
+}
+
+func (c *clientLock) started() {
+	if c.lock != nil && c.inherited {
+		c.lock.Close()
+	}
+}
+
+func (c *clientLock) exited() {
+	if c.waits() {
+		c.lock.Release()
+	}
+}
+
+func (c *clientLock) failed() {
+	releaseLock(c.lock)
+}
+
+func releaseLock(lock *filelock.Lock) {
+	if lock != nil {
+		lock.Release()
+	}
+}
@@ -0,0 +1,94 @@
+package launch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+)
+
+// TestFakeClient is the client started by TestLaunchedClientHoldsLock. It
+// runs until the file named by FAKE_CLIENT_QUIT appears.
+func TestFakeClient(t *testing.T) {
+	quit := os.Getenv("FAKE_CLIENT_QUIT")
+	if quit == "" {
+		t.Skip("only run as a fake client")
+	}
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(quit); err == nil {
+			return
+		}
+	}
+}
+
+func TestLaunchedClientHoldsLock(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	clientDir := filepath.Join(dir, "Client2016")
+	quit := filepath.Join(dir, "quit")
+
+	for _, supervise := range []bool{false, true} {
+		os.Remove(quit)
+		lock, err := install.LockClient(clientDir, filelock.Shared, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := Options{
+			Executable: exe,
+			ExtraArgs:  []string{"-test.run=^TestFakeClient$"},
+			Env:        map[string]string{"FAKE_CLIENT_QUIT": quit},
+			Lock:       lock,
+		}
+		if supervise {
+			opts.LogFile = filepath.Join(dir, "client.log")
+			_, err = Supervise(ExecSpawner{}, opts, 100*time.Millisecond)
+		} else {
+			err = Client(ExecSpawner{}, opts)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lock, err := filelock.TryAcquire(install.LockPath(clientDir), filelock.Exclusive); !errors.Is(err, filelock.ErrLocked) {
+			if err == nil {
+				lock.Release()
+			}
+			t.Fatalf("supervise=%v: exclusive lock while the client runs = %v, want ErrLocked", supervise, err)
+		}
+
+		exclusive := make(chan error, 1)
+		waited := false
+		go func() {
+			lock, err := install.LockClient(clientDir, filelock.Exclusive, func() { waited = true })
+			if err == nil {
+				lock.Release()
+			}
+			exclusive <- err
+		}()
+		select {
+		case err := <-exclusive:
+			t.Fatalf("supervise=%v: exclusive lock taken while the client runs: %v", supervise, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		os.WriteFile(quit, nil, 0644)
+		select {
+		case err := <-exclusive:
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !waited {
+				t.Errorf("supervise=%v: LockClient did not report waiting", supervise)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("supervise=%v: exclusive lock not taken after the client exited", supervise)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package launch
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sylicitybootstrapper/clientlaunchcalls"
+)
+
+// Exit describes a client that quit with a non-zero code while supervised.
+type Exit struct {
+	Code    int
+	After   time.Duration
+	LogFile string
+	// Excerpt is the tail of the client's output.
+	Excerpt string
+}
+
+func (e *Exit) String() string {
+	return fmt.Sprintf("exited with code %d after %s", e.Code, e.After.Round(time.Second/10))
+}
+
+// Process is a started client that can still be waited for.
+type Process interface {
+	Wait() (code int, err error)
+}
+
+// Starter starts a client and keeps a handle on it. Output goes to logFile
+// when set.
+type Starter interface {
+	Start(cmd Command, logFile string) (Process, error)
+}
+
+// Supervise starts the client and watches it for window. A client that exits
+// with a non-zero code in that time is returned as an Exit; one that exits
+// cleanly or is still running afterwards returns nil.
+func Supervise(starter Starter, opts Options, window time.Duration) (*Exit, error) {
+	if _, err := os.Stat(opts.Executable); os.IsNotExist(err) {
+		releaseLock(opts.Lock)
+		return nil, fmt.Errorf("client executable not found: %s", opts.Executable)
+	}
+
+	cmd := BuildCommand(opts)
+	slog.Info("launching supervised client", "command", cmd.String(), "env", len(opts.Env), "log", opts.LogFile)
+	started := time.Now()
+	proc, err := starter.Start(cmd, opts.LogFile)
+	if err != nil {
+		slog.Error("client launch failed", "err", err)
+		return nil, err
+	}
+
+	done := make(chan int, 1)
+	go func() {
+		code, err := proc.Wait()
+		if err != nil {
+			slog.Warn("could not wait for client", "err", err)
+			code = -1
+		}
+		done <- code
+	}()
+
+	select {
+	case code := <-done:
+		after := time.Since(started)
+		if code == 0 {
+			slog.Info("client exited cleanly", "after", after)
+			return nil, nil
+		}
+		exit := &Exit{Code: code, After: after, LogFile: opts.LogFile, Excerpt: tail(opts.LogFile, 20)}
+		slog.Error("client crashed", "code", code, "after", after)
+		return exit, nil
+	case <-time.After(window):
+		slog.Info("client still running, no longer supervising", "after", window)
+		return nil, nil
+	}
+}
+
+func tail(path string, lines int) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	all := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n")
+}
+
+// Start runs the client in its own session like Spawn, but keeps the handle
+// so it can be waited for. Output goes to a file rather than a pipe, so the
+// client is not hurt when the bootstrapper exits first.
+func (ExecSpawner) Start(command Command, logFile string) (Process, error) {
+	cmd := exec.Command(command.Path, command.Args...)
+	clientlaunchcalls.SetupProcAttr(cmd)
+	cmd.Env = command.Env
+	lock := holdLock(cmd, command.Lock)
+
+	if logFile != "" {
+		if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+			lock.failed()
+			return nil, err
+		}
+		out, err := os.Create(logFile)
+		if err != nil {
+			lock.failed()
+			return nil, err
+		}
+		defer out.Close()
+		cmd.Stdout, cmd.Stderr = out, out
+	}
+
+	if err := cmd.Start(); err != nil {
+		lock.failed()
+		return nil, fmt.Errorf("failed to start client: %w", err)
+	}
+	lock.started()
+	return execProcess{cmd, lock}, nil
+}
+
+type execProcess struct {
+	cmd  *exec.Cmd
+	lock *clientLock
+}
+
+func (p execProcess) Wait() (int, error) {
+	err := p.cmd.Wait()
+	p.lock.exited()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
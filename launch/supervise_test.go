@@ -0,0 +1,63 @@
+package launch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeStarter struct {
+	code   int
+	delay  time.Duration
+	output string
+}
+
+type fakeProcess struct {
+	code  int
+	delay time.Duration
+}
+
+func (s fakeStarter) Start(cmd Command, logFile string) (Process, error) {
+	if logFile != "" {
+		os.WriteFile(logFile, []byte(s.output), 0644)
+	}
+	return fakeProcess{s.code, s.delay}, nil
+}
+
+func (p fakeProcess) Wait() (int, error) {
+	time.Sleep(p.delay)
+	return p.code, nil
+}
+
+func TestSupervise(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "SylicityPlayerBeta.exe")
+	os.WriteFile(exe, nil, 0755)
+	opts := Options{Executable: exe, LogFile: filepath.Join(dir, "client.log")}
+
+	var output strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&output, "line %d\n", i)
+	}
+
+	exit, err := Supervise(fakeStarter{code: 3, output: output.String()}, opts, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exit == nil || exit.Code != 3 {
+		t.Fatalf("crash not reported: %+v", exit)
+	}
+	if lines := strings.Split(exit.Excerpt, "\n"); len(lines) != 20 || lines[19] != "line 29" {
+		t.Errorf("excerpt is not the last 20 lines: %q", exit.Excerpt)
+	}
+
+	if exit, err := Supervise(fakeStarter{code: 0}, opts, time.Second); err != nil || exit != nil {
+		t.Errorf("clean exit reported as %+v, %v", exit, err)
+	}
+	if exit, err := Supervise(fakeStarter{code: 1, delay: time.Second}, opts, 50*time.Millisecond); err != nil || exit != nil {
+		t.Errorf("exit after the window reported as %+v, %v", exit, err)
+	}
+}
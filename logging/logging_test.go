@@ -0,0 +1,156 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatedName(t *testing.T) {
+	path := filepath.Join("logs", FileName)
+	want := []string{path, path + ".1", path + ".2", path + ".3"}
+	for i, name := range want {
+		if got := rotatedName(path, i); got != name {
+			t.Errorf("rotatedName(%d) = %q, want %q", i, got, name)
+		}
+	}
+}
+
+func TestRotatingFileRotatesAtMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	r, err := OpenRotating(path, 10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("aaaa\n"))
+	r.Write([]byte("bbbb\n"))
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatal("rotated before reaching maxSize")
+	}
+	// This write would take the file past maxSize, so it starts a new one.
+	r.Write([]byte("cccc\n"))
+
+	if got := readFile(t, path+".1"); got != "aaaa\nbbbb\n" {
+		t.Errorf("%s.1 = %q", FileName, got)
+	}
+	if got := readFile(t, path); got != "cccc\n" {
+		t.Errorf("%s = %q", FileName, got)
+	}
+}
+
+func TestRotatingFileKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	r, err := OpenRotating(path, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each write fills a file, so every later one rotates.
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n", "5\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	want := map[string]string{FileName: "5\n", FileName + ".1": "4\n", FileName + ".2": "3\n"}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != len(want) {
+		t.Errorf("%d files in the log dir, want %d", len(entries), len(want))
+	}
+	for name, content := range want {
+		if got := readFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+
+	var names []string
+	for _, file := range Files(dir) {
+		names = append(names, filepath.Base(file))
+	}
+	if want := []string{FileName, FileName + ".1", FileName + ".2"}; !slices.Equal(names, want) {
+		t.Errorf("Files = %v, want %v", names, want)
+	}
+}
+
+func TestRotatingFileAppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	os.WriteFile(path, []byte("12345678\n"), 0644)
+
+	r, err := OpenRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("new\n"))
+	r.Close()
+
+	// The size of what was already there counts towards maxSize.
+	if got := readFile(t, path+".1"); got != "12345678\n" {
+		t.Errorf("%s.1 = %q", FileName, got)
+	}
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("%s = %q", FileName, got)
+	}
+}
+
+func TestTeeRespectsEachLevel(t *testing.T) {
+	var debug, warn bytes.Buffer
+	logger := slog.New(tee{
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&warn, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	}).With("session", "abc")
+
+	logger.Debug("details")
+	logger.Info("progress")
+	logger.Warn("careful")
+	logger.WithGroup("client").Error("crashed", "code", 3)
+
+	for _, msg := range []string{"details", "progress", "careful", "crashed"} {
+		if !strings.Contains(debug.String(), "msg="+msg) {
+			t.Errorf("debug handler is missing %q:\n%s", msg, debug.String())
+		}
+	}
+	for _, msg := range []string{"details", "progress"} {
+		if strings.Contains(warn.String(), "msg="+msg) {
+			t.Errorf("warn handler got %q:\n%s", msg, warn.String())
+		}
+	}
+	for _, want := range []string{"msg=careful", "session=abc", "client.code=3"} {
+		if !strings.Contains(warn.String(), want) {
+			t.Errorf("warn handler is missing %q:\n%s", want, warn.String())
+		}
+	}
+
+	quiet := tee{slog.NewTextHandler(&warn, &slog.HandlerOptions{Level: slog.LevelError})}
+	if quiet.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("tee enabled below every handler's level")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "INFO": slog.LevelInfo, "warn": slog.LevelWarn, "error": slog.LevelError} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+}
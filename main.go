@@ -1,17 +1,11 @@
 package main
 
 import (
-	"archive/zip"
-	"crypto/sha1"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"image/color"
-	"io"
-	"net/http"
+	"log/slog"
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -19,7 +13,20 @@ import (
 	"time"
 
 	"sylicitybootstrapper/themecode"
-	"sylicitybootstrapper/clientlaunchcalls"
+	"sylicitybootstrapper/assets"
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/customtheme"
+	"sylicitybootstrapper/download"
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/instance"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/launch"
+	"sylicitybootstrapper/logging"
+	"sylicitybootstrapper/manifest"
+	"sylicitybootstrapper/progress"
+	"sylicitybootstrapper/selfupdate"
+	"sylicitybootstrapper/uninstall"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -31,19 +38,90 @@ import (
 )
 
 const (
-	versionURL      = "https://setup.no.lol/version" // currently placeholder
-	downloadURLBase = "https://setup.no.lol/" // currently placeholder
-	appName         = "Sylicity"
-	protocolScheme  = "sylicity-player" // for later
-	authURLDefault  = "https://www.kroner.lol/Login/Negotiate.ashx"
-	clientVersionsAPI = "https://clientversions.no.lol/v1/client-versions" // currently placeholder
+	appName        = "Sylicity"
+	protocolScheme = "sylicity-player"
 )
 
+// Overridden at release time with -ldflags "-X main.buildVersion=1.2.3".
+var buildVersion = "dev"
+
 type greenTheme struct {
 	fyne.Theme
+	style themeStyle
+}
+
+// themeStyle is what the theme takes from the system and the theme file
+// besides the variant.
+type themeStyle struct {
+	// accent replaces the greens when set.
+	accent       color.Color
+	highContrast bool
+	// custom is the theme file from the app dir, if there is one.
+	custom *customtheme.Theme
+}
+
+// newGreenTheme builds on the light or dark default theme. The theme is
+// replaced with a new one when the system variant changes.
+func newGreenTheme(variant fyne.ThemeVariant, style themeStyle) *greenTheme {
+	if variant == theme.VariantLight {
+		return &greenTheme{Theme: theme.LightTheme(), style: style}
+	}
+	return &greenTheme{Theme: theme.DarkTheme(), style: style}
+}
+
+// highContrastColors replace the default colors when high contrast is on.
+var highContrastColors = map[fyne.ThemeVariant]map[fyne.ThemeColorName]color.Color{
+	theme.VariantDark: {
+		theme.ColorNameBackground:          color.Black,
+		theme.ColorNameForeground:          color.White,
+		theme.ColorNameButton:              color.NRGBA{R: 0x1A, G: 0x1A, B: 0x1A, A: 0xFF},
+		theme.ColorNameDisabled:            color.NRGBA{R: 0xBF, G: 0xBF, B: 0xBF, A: 0xFF},
+		theme.ColorNamePlaceHolder:         color.NRGBA{R: 0xBF, G: 0xBF, B: 0xBF, A: 0xFF},
+		theme.ColorNameInputBackground:     color.Black,
+		theme.ColorNameInputBorder:         color.White,
+		theme.ColorNameSeparator:           color.White,
+		theme.ColorNamePrimary:             color.NRGBA{R: 0x00, G: 0xFF, B: 0x7F, A: 0xFF},
+		theme.ColorNameForegroundOnPrimary: color.Black,
+		theme.ColorNameHover:               color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0x40},
+		theme.ColorNameFocus:               color.NRGBA{R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+	},
+	theme.VariantLight: {
+		theme.ColorNameBackground:          color.White,
+		theme.ColorNameForeground:          color.Black,
+		theme.ColorNameButton:              color.NRGBA{R: 0xE6, G: 0xE6, B: 0xE6, A: 0xFF},
+		theme.ColorNameDisabled:            color.NRGBA{R: 0x59, G: 0x59, B: 0x59, A: 0xFF},
+		theme.ColorNamePlaceHolder:         color.NRGBA{R: 0x59, G: 0x59, B: 0x59, A: 0xFF},
+		theme.ColorNameInputBackground:     color.White,
+		theme.ColorNameInputBorder:         color.Black,
+		theme.ColorNameSeparator:           color.Black,
+		theme.ColorNamePrimary:             color.NRGBA{R: 0x00, G: 0x6B, B: 0x1F, A: 0xFF},
+		theme.ColorNameForegroundOnPrimary: color.White,
+		theme.ColorNameHover:               color.NRGBA{R: 0x00, G: 0x00, B: 0x00, A: 0x33},
+		theme.ColorNameFocus:               color.NRGBA{R: 0x00, G: 0x00, B: 0xCC, A: 0xFF},
+	},
 }
 
 func (m *greenTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if m.style.highContrast {
+		if c, ok := highContrastColors[variant][name]; ok {
+			return c
+		}
+	}
+	if m.style.custom != nil {
+		if c, ok := m.style.custom.Colors[variant][name]; ok {
+			return c
+		}
+	}
+	if m.style.accent != nil {
+		switch name {
+		case theme.ColorNamePrimary:
+			return m.style.accent
+		case theme.ColorNameHover:
+			return withAlpha(m.style.accent, 0x26)
+		case theme.ColorNameFocus:
+			return withAlpha(m.style.accent, 0x7f)
+		}
+	}
 	if name == theme.ColorNamePrimary {
 		if variant == theme.VariantDark {
 			return color.NRGBA{R: 0x6B, G: 0xE4, B: 0x8F, A: 0xFF}
@@ -53,49 +131,181 @@ func (m *greenTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant)
 	return m.Theme.Color(name, variant)
 }
 
+func (m *greenTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if m.style.custom != nil && !style.Symbol {
+		key := fyne.TextStyle{Bold: style.Bold, Italic: style.Italic}
+		if style.Monospace {
+			key = fyne.TextStyle{Monospace: true}
+		}
+		if font, ok := m.style.custom.Fonts[key]; ok {
+			return font
+		}
+	}
+	return m.Theme.Font(style)
+}
+
+func (m *greenTheme) Size(name fyne.ThemeSizeName) float32 {
+	if m.style.custom != nil {
+		if size, ok := m.style.custom.Sizes[name]; ok {
+			return size
+		}
+	}
+	return m.Theme.Size(name)
+}
+
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = alpha
+	return nrgba
+}
+
 type LaunchOptions struct {
 	LaunchMode string
 	Script     string
 	AuthTicket string
 	ClientYear string
-}
+	Channel    string
+
+	// Headless runs the requested mode in the terminal without a window.
+	Headless   bool
+	JSONOutput bool
+	KeepData   bool
+	PurgeData  bool
 
-type ClientInfo struct {
-	Hash string `json:"hash"`
-	URL  string `json:"url"`
+	ConfigPath      string
+	ConfigOverrides map[string]string
+
+	// OutputPath is where -diagnostics writes its bundle.
+	OutputPath string
 }
 
-type ClientVersionsResponse struct {
-	Clients map[string]ClientInfo `json:"clients"`
+// LogValue keeps the auth ticket and join script out of the log.
+func (o LaunchOptions) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("mode", o.LaunchMode),
+		slog.String("clientYear", o.ClientYear),
+		slog.String("channel", o.Channel),
+		slog.Bool("hasTicket", o.AuthTicket != ""),
+		slog.Bool("hasScript", o.Script != ""),
+		slog.Bool("headless", o.Headless),
+		slog.Bool("json", o.JSONOutput),
+		slog.String("config", o.ConfigPath),
+		slog.Any("overrides", o.ConfigOverrides),
+	)
 }
 
 func main() {
-	launchOpts, err := parseLaunchOptions()
-	if err != nil {
-		fmt.Printf("Argument parsing error: %v\n", err)
+	selfupdate.WaitForPrevious()
+	selfupdate.CleanupOld()
+
+	launchOpts, argErr := parseLaunchOptions()
+
+	cfg, cfgErr := loadConfig(launchOpts)
+	if cfgErr == nil {
+		if launchOpts.Channel == "" {
+			launchOpts.Channel = cfg.Channel
+		}
+		_, cfgErr = cfg.ManifestURL(launchOpts.Channel)
 	}
 
-	myApp := app.New()
-	myWindow := myApp.NewWindow("Sylicity Installer")
+	closeLog := setupLogging(cfg)
+	defer closeLog()
+	if cfg != nil {
+		l10n.Setup(cfg.Language)
+	} else {
+		l10n.Setup("")
+	}
+	slog.Info("bootstrapper starting", "version", buildVersion, "os", runtime.GOOS, "arch", runtime.GOARCH)
+	slog.Info("parsed launch options", "options", launchOpts)
+	if argErr != nil {
+		slog.Error("argument parsing failed", "err", argErr)
+	}
+	if cfgErr != nil {
+		slog.Error("configuration error", "err", cfgErr)
+	}
 
-	detectedVariant := themecode.DetectSystemTheme()
-	var baseTheme fyne.Theme
-	if detectedVariant == theme.VariantLight {
-		baseTheme = theme.LightTheme()
+	if launchOpts.Headless {
+		if cfgErr != nil && launchOpts.LaunchMode != "diagnostics" {
+			closeLog()
+			os.Exit(2)
+		}
+		status := runCLI(cfg, launchOpts)
+		slog.Info("finished", "status", status)
+		closeLog()
+		os.Exit(status)
+	}
+
+	// Only the window claims the app dir. A second window hands its request
+	// to the first and exits; CLI runs rely on the client locks instead.
+	var primary *instance.Primary
+	if cfgErr == nil {
+		var forwarded bool
+		if primary, forwarded = claimInstance(launchOpts); forwarded {
+			closeLog()
+			return
+		}
+	}
+
+	myApp := app.New()
+	myApp.SetIcon(assets.Logo)
+	windowTitle := l10n.T("installer.title")
+	if launchOpts.Channel != "" && launchOpts.Channel != config.StableChannel {
+		windowTitle = l10n.T("installer.titleWithChannel", "Channel", launchOpts.Channel)
+	}
+	myWindow := myApp.NewWindow(windowTitle)
+
+	access := themecode.DetectAccessibility()
+	slog.Info("accessibility preferences", "highContrast", access.HighContrast, "reducedMotion", access.ReducedMotion)
+	style := themeStyle{highContrast: access.HighContrast}
+	if cfg != nil && cfg.Theme.SystemAccent {
+		if detected, ok := themecode.DetectAccentColor(); ok {
+			style.accent = detected
+		} else {
+			slog.Info("no system accent color found, keeping the built-in green")
+		}
+	}
+	if appDir, err := getAppDir(); err == nil {
+		// A broken theme file must not stop the bootstrapper; the built-in
+		// theme is used instead.
+		if custom, err := customtheme.Load(appDir); err != nil {
+			slog.Error("ignoring theme file", "err", err)
+		} else if custom != nil {
+			slog.Info("using theme file", "path", filepath.Join(appDir, customtheme.FileName))
+			style.custom = custom
+		}
+	}
+	myApp.Settings().SetTheme(newGreenTheme(themecode.DetectSystemTheme(), style))
+	if stopWatching, err := themecode.Watch(func(variant fyne.ThemeVariant) {
+		slog.Info("system theme changed", "variant", variant)
+		fyne.Do(func() { myApp.Settings().SetTheme(newGreenTheme(variant, style)) })
+	}); err != nil {
+		slog.Debug("not following system theme changes", "err", err)
 	} else {
-		baseTheme = theme.DarkTheme()
+		defer stopWatching()
 	}
-	myApp.Settings().SetTheme(&greenTheme{Theme: baseTheme})
 
-	logoImage := canvas.NewImageFromFile("Sylicity.png")
+	var logo fyne.Resource = assets.Logo
+	if style.custom != nil && style.custom.Logo != nil {
+		logo = style.custom.Logo
+	}
+	logoImage := canvas.NewImageFromResource(logo)
 	logoImage.FillMode = canvas.ImageFillContain
 	logoImage.SetMinSize(fyne.NewSize(96, 96))
 
-	statusLabel := widget.NewLabel("Preparing to install...")
+	statusLabel := widget.NewLabel(l10n.T("installer.preparing"))
 	statusLabel.Alignment = fyne.TextAlignCenter
 
+	channelLabel := widget.NewLabelWithStyle(l10n.T("installer.channel", "Channel", launchOpts.Channel), fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	if launchOpts.Channel == "" {
+		channelLabel.Hide()
+	}
+
 	customLoader, track, chunkToAnimate := createCustomLoader()
-	cancelButton := widget.NewButton("Cancel", func() { myApp.Quit() })
+	cancelButton := widget.NewButton(l10n.T("common.cancel"), func() { myApp.Quit() })
+	manageButton := widget.NewButton(l10n.T("installer.manageClients"), func() { showClientManager(cfg, launchOpts.Channel, myWindow) })
+	manageButton.Hide()
+	diagnosticsButton := widget.NewButton(l10n.T("common.exportDiagnostics"), func() { showExportDiagnostics(cfg, launchOpts, myWindow) })
+	diagnosticsButton.Hide()
 
 	verticalSpacer := canvas.NewRectangle(color.Transparent)
 	verticalSpacer.SetMinSize(fyne.NewSize(0, 12))
@@ -106,7 +316,8 @@ func main() {
 		statusLabel,
 		customLoader,
 		verticalSpacer,
-		container.NewCenter(cancelButton),
+		container.NewCenter(container.NewHBox(manageButton, diagnosticsButton, cancelButton)),
+		channelLabel,
 		layout.NewSpacer(),
 	)
 
@@ -116,38 +327,79 @@ func main() {
 	myWindow.CenterOnScreen()
 
 	loaderWidth := float32(440) - theme.Padding()*4
-	go runInstallerLogic(launchOpts, statusLabel, customLoader, track, chunkToAnimate, loaderWidth, cancelButton, myWindow)
+	if cfgErr != nil {
+		statusLabel.SetText(l10n.T("installer.invalidConfig", "Err", cfgErr))
+		customLoader.Hide()
+		diagnosticsButton.Show()
+		cancelButton.SetText(l10n.T("common.close"))
+		myWindow.ShowAndRun()
+		return
+	}
+
+	view := newInstallerView(statusLabel, customLoader, track, chunkToAnimate, loaderWidth, cancelButton, manageButton, diagnosticsButton, myWindow, content)
+	view.reducedMotion = access.ReducedMotion
+	start := func(opts LaunchOptions) {
+		switch opts.LaunchMode {
+		case "uninstall":
+			showUninstallView(cfg, myWindow)
+		case "manage":
+			showClientManager(cfg, opts.Channel, myWindow)
+		default:
+			if myWindow.Content() != content {
+				myWindow.SetContent(content)
+				myWindow.Resize(fyne.NewSize(440, 280))
+				myWindow.SetFixedSize(true)
+			}
+			startInstallerRun(cfg, opts, view)
+		}
+	}
+	if primary != nil {
+		defer primary.Close()
+		view.queue = &requestQueue{win: myWindow, view: view, cfg: cfg, start: start}
+		go view.queue.serve(primary)
+	}
+	start(launchOpts)
 
 	myWindow.ShowAndRun()
 }
 
+// startInstallerRun runs an install, play, verify or repair request in view.
+func startInstallerRun(cfg *config.Config, opts LaunchOptions, view *installerView) {
+	repairOpts := opts
+	repairOpts.LaunchMode = "repair"
+	if repairOpts.ClientYear == "" && opts.LaunchMode != "verify" && opts.LaunchMode != "repair" {
+		repairOpts.ClientYear = cfg.DefaultClientYear
+	}
+	view.onRepair = func() {
+		view.run(func(events chan<- installerEvent) { runVerifyLogic(cfg, repairOpts, events) })
+	}
+
+	if opts.LaunchMode == "verify" || opts.LaunchMode == "repair" {
+		view.onRetry = nil
+		view.run(func(events chan<- installerEvent) { runVerifyLogic(cfg, opts, events) })
+		return
+	}
+	view.onRetry = func() {
+		view.run(func(events chan<- installerEvent) { runInstallerLogic(cfg, opts, events) })
+	}
+	view.onRetry()
+}
+
 func createCustomLoader() (fyne.CanvasObject, *canvas.Rectangle, *canvas.Rectangle) {
 	track := canvas.NewRectangle(theme.DisabledColor())
 	chunk := canvas.NewRectangle(theme.PrimaryColor())
+	fyne.CurrentApp().Settings().AddListener(func(fyne.Settings) {
+		fyne.Do(func() {
+			track.FillColor = theme.DisabledColor()
+			chunk.FillColor = theme.PrimaryColor()
+			track.Refresh()
+			chunk.Refresh()
+		})
+	})
 	loader := container.NewWithoutLayout(track, chunk)
 	return loader, track, chunk
 }
 
-func animateIndeterminate(chunk *canvas.Rectangle, totalWidth float32, stop chan bool, wg *sync.WaitGroup) {
-	defer wg.Done()
-	chunkWidth := float32(80)
-	chunk.Resize(fyne.NewSize(chunkWidth, 8))
-	var xPos float32 = -chunkWidth
-	for {
-		select {
-		case <-stop:
-			return
-		default:
-			xPos += 4
-			if xPos > totalWidth {
-				xPos = -chunkWidth
-			}
-			chunk.Move(fyne.NewPos(xPos, 0))
-			time.Sleep(16 * time.Millisecond)
-		}
-	}
-}
-
 func parseLaunchOptions() (LaunchOptions, error) {
 	opts := LaunchOptions{LaunchMode: "install"}
 
@@ -165,7 +417,7 @@ func parseLaunchOptions() (LaunchOptions, error) {
 		return parseProtocolArgs(normalized)
 	}
 
-	if firstArg == "-play" {
+	if strings.HasPrefix(firstArg, "-") {
 		return parseCommandLineArgs(os.Args)
 	}
 
@@ -194,6 +446,8 @@ func parseProtocolArgs(arg string) (LaunchOptions, error) {
 				opts.AuthTicket = value
 			case "clientyear":
 				opts.ClientYear = value
+			case "channel":
+				opts.Channel = value
 			}
 		}
 	}
@@ -206,11 +460,43 @@ func parseProtocolArgs(arg string) (LaunchOptions, error) {
 }
 
 func parseCommandLineArgs(args []string) (LaunchOptions, error) {
-	opts := LaunchOptions{LaunchMode: "play"}
+	opts := LaunchOptions{LaunchMode: "install", ConfigOverrides: map[string]string{}}
+	setOverride := func(i int, key string) int {
+		if i+1 < len(args) {
+			opts.ConfigOverrides[key] = args[i+1]
+			return i + 1
+		}
+		return i
+	}
 	for i := 1; i < len(args); i++ {
 		switch args[i] {
 		case "-play":
 			opts.LaunchMode = "play"
+		case "-uninstall":
+			opts.LaunchMode = "uninstall"
+		case "-verify":
+			opts.LaunchMode = "verify"
+		case "-repair":
+			opts.LaunchMode = "repair"
+		case "-manage":
+			opts.LaunchMode = "manage"
+		case "-diagnostics":
+			opts.LaunchMode = "diagnostics"
+			opts.Headless = true
+		case "-out":
+			if i+1 < len(args) {
+				opts.OutputPath = args[i+1]
+				i++
+			}
+		case "-cli":
+			opts.Headless = true
+		case "-json":
+			opts.Headless = true
+			opts.JSONOutput = true
+		case "-keepdata":
+			opts.KeepData = true
+		case "-purge":
+			opts.PurgeData = true
 		case "-script":
 			if i+1 < len(args) {
 				opts.Script = args[i+1]
@@ -226,339 +512,182 @@ func parseCommandLineArgs(args []string) (LaunchOptions, error) {
 				opts.ClientYear = args[i+1]
 				i++
 			}
+		case "-config":
+			if i+1 < len(args) {
+				opts.ConfigPath = args[i+1]
+				i++
+			}
+		case "-installdir":
+			i = setOverride(i, "installDir")
+		case "-concurrency":
+			i = setOverride(i, "downloadConcurrency")
+		case "-bwlimit":
+			i = setOverride(i, "bandwidthLimit")
+		case "-bwschedule":
+			i = setOverride(i, "bandwidthSchedule")
+		case "-channel":
+			if i+1 < len(args) {
+				opts.Channel = args[i+1]
+				i++
+			}
+		case "-set":
+			if i+1 < len(args) {
+				kv := strings.SplitN(args[i+1], "=", 2)
+				if len(kv) != 2 {
+					return opts, fmt.Errorf("-set expects key=value, got %q", args[i+1])
+				}
+				opts.ConfigOverrides[kv[0]] = kv[1]
+				i++
+			}
 		}
 	}
 	return opts, nil
 }
 
-func getClientVersions() (map[string]ClientInfo, error) {
-	resp, err := http.Get(clientVersionsAPI)
+// Precedence, lowest first: built-in defaults, config file, SYLICITY_*
+// environment variables, command line flags.
+func loadConfig(opts LaunchOptions) (*config.Config, error) {
+	appDir, err := getAppDir()
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status: %s", resp.Status)
-	}
 
-	var data ClientVersionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	path := opts.ConfigPath
+	if path == "" {
+		path = filepath.Join(appDir, config.FileName)
 	}
 
-	return data.Clients, nil
-}
-
-func getSHA1Hash(path string) (string, error) {
-	file, err := os.Open(path)
+	cfg, err := config.Load(path, appDir)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer file.Close()
-
-	hash := sha1.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
 	}
-
-	return hex.EncodeToString(hash.Sum(nil)), nil
-}
-// TODO: add an actual check
-func checkAndUpdateClients(appDir string, onProgress func(string, float32), forceInstall bool) error {
-	clients, err := getClientVersions()
-	if err != nil {
-		return fmt.Errorf("failed to fetch client versions: %w", err)
+	if err := cfg.ApplyOverrides(opts.ConfigOverrides); err != nil {
+		return nil, err
 	}
-
-	versionsDir := filepath.Join(appDir, "Versions")
-	if err := os.MkdirAll(versionsDir, 0755); err != nil {
-		return err
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	for year, info := range clients {
-		clientDir := filepath.Join(versionsDir, fmt.Sprintf("Client%s", year))
-		exePath := filepath.Join(clientDir, "SylicityPlayerBeta.exe")
-
-		needsInstall := forceInstall
-		if !forceInstall {
-			if _, err := os.Stat(exePath); os.IsNotExist(err) {
-				needsInstall = true
-			}
-		}
-
-		if !needsInstall {
-			fmt.Printf("Client %s exists, skipping (forceInstall=false).\n", year)
-			continue
-		}
-
-		fmt.Printf("Installing client %s...\n", year)
-		onProgress(fmt.Sprintf("Installing client %s...", year), 0)
-
-		if err := os.RemoveAll(clientDir); err != nil && !os.IsNotExist(err) {
-			return err
-		}
-		if err := os.MkdirAll(clientDir, 0755); err != nil {
-			return err
-		}
-
-		zipPath, err := downloadClientZip(info.URL, func(p float32) {
-			onProgress(fmt.Sprintf("Downloading client %s...", year), p)
-		})
-		if err != nil {
-			return fmt.Errorf("failed to download client %s: %w", year, err)
-		}
+// runInstallerLogic installs or updates the clients and, in play mode,
+// launches one. It only reports through events and closes the channel when
+// it returns.
+func runInstallerLogic(cfg *config.Config, opts LaunchOptions, events chan<- installerEvent) {
+	defer close(events)
 
-		if err := unzipToDir(zipPath, clientDir); err != nil {
-			os.Remove(zipPath)
-			return fmt.Errorf("failed to extract client %s: %w", year, err)
+	if os.Getenv(selfupdate.RelaunchedEnv) == "" {
+		events <- statusEvent(l10n.T("installer.checkingUpdates"))
+		if err := checkForSelfUpdate(cfg, func(msg string) { events <- statusEvent(msg) }); err != nil {
+			slog.Warn("self-update failed", "err", err)
 		}
-		os.Remove(zipPath)
-
-		onProgress(fmt.Sprintf("Installed client %s", year), 1)
 	}
 
-	return nil
-}
-// TODO: improve this
-func downloadSpecificClient(appDir, clientYear string, onProgress func(string, float32), forceInstall bool) error {
-	clients, err := getClientVersions()
-	if err != nil {
-		return fmt.Errorf("failed to fetch client versions: %w", err)
-	}
-
-	info, exists := clients[clientYear]
-	if !exists {
-		return fmt.Errorf("client year %s not available", clientYear)
-	}
-
-	versionsDir := filepath.Join(appDir, "Versions")
-	clientDir := filepath.Join(versionsDir, fmt.Sprintf("Client%s", clientYear))
-	exePath := filepath.Join(clientDir, "SylicityPlayerBeta.exe")
+	events <- statusEvent(l10n.T("installer.checkingInstall"))
 
-	needsInstall := forceInstall
-	if !forceInstall {
-		if _, err := os.Stat(exePath); os.IsNotExist(err) {
-			needsInstall = true
-		}
-	}
+	forceInstall := opts.LaunchMode != "play"
+	tracker := progress.NewTracker(progress.InstallStages, func(snapshot progress.Snapshot) {
+		events <- installerEvent{Kind: eventProgress, Progress: snapshot}
+	})
 
-	if !needsInstall {
-		fmt.Printf("Client %s exists, skipping (forceInstall=false).\n", clientYear)
-		return nil
+	if err := installClients(cfg, opts.Channel, opts.ClientYear, tracker, forceInstall); err != nil {
+		slog.Error("installing clients failed", "err", err)
+		events <- failedEvent(l10n.T("installer.installFailed"), err)
+		return
 	}
 
-	fmt.Printf("Installing client %s...\n", clientYear)
-	onProgress(fmt.Sprintf("Installing client %s...", clientYear), 0)
+	registerSylicity(tracker)
 
-	if err := os.RemoveAll(clientDir); err != nil && !os.IsNotExist(err) {
-		return err
-	}
-	if err := os.MkdirAll(clientDir, 0755); err != nil {
-		return err
+	if opts.LaunchMode != "play" {
+		events <- installerEvent{Kind: eventDone, Message: l10n.T("installer.ready")}
+		return
 	}
 
-	zipPath, err := downloadClientZip(info.URL, func(p float32) {
-		onProgress(fmt.Sprintf("Downloading client %s...", clientYear), p)
-	})
+	events <- statusEvent(l10n.T("installer.starting"))
+	exit, err := launchClient(cfg, opts, func() { events <- statusEvent(install.WaitingMessage()) })
 	if err != nil {
-		return fmt.Errorf("failed to download client: %w", err)
+		slog.Error("launching client failed", "err", err)
+		events <- failedEvent(l10n.T("installer.launchFailed"), err)
+		return
 	}
-
-	if err := unzipToDir(zipPath, clientDir); err != nil {
-		os.Remove(zipPath)
-		return fmt.Errorf("failed to extract client: %w", err)
+	if exit != nil {
+		events <- installerEvent{Kind: eventCrashed, Message: l10n.T("crash.status", "Code", exit.Code), Crash: exit}
+		return
 	}
-	os.Remove(zipPath)
-
-	onProgress(fmt.Sprintf("Installed client %s", clientYear), 1)
-	return nil
+	events <- installerEvent{Kind: eventLaunched}
 }
 
-
-func downloadClientZip(urlStr string, onProgress func(float32)) (string, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", urlStr, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("User-Agent", "Roblox/WinInet")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+func checkForSelfUpdate(cfg *config.Config, onStatus func(string)) error {
+	if buildVersion == "dev" {
+		slog.Debug("development build, skipping self-update check")
+		return nil
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("bad status: %s", resp.Status)
+	if !selfupdate.Enabled() {
+		slog.Debug("no update key built in, skipping self-update check")
+		return nil
 	}
 
-	out, err := os.CreateTemp("", "client-*.zip")
+	release, bin, err := selfupdate.Check(cfg.VersionURL, buildVersion)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if bin == nil {
+		slog.Info("bootstrapper is up to date", "version", buildVersion)
+		return nil
 	}
-	defer out.Close()
 
-	writer := &ProgressWriter{
-		Total:      resp.ContentLength,
-		File:       out,
-		OnProgress: onProgress,
+	slog.Info("updating bootstrapper", "from", buildVersion, "to", release.Version)
+	onStatus(l10n.T("installer.selfUpdating", "Version", release.Version))
+	if err := selfupdate.Apply(downloadSource(cfg), *bin); err != nil {
+		return err
 	}
 
-	_, err = io.Copy(writer, resp.Body)
-	return out.Name(), err
+	// os.Args[1:] still holds the protocol URI or -play arguments, so the
+	// relaunched bootstrapper picks up the pending launch where we left off.
+	return selfupdate.Relaunch(os.Args[1:])
 }
 
-func unzipToDir(zipPath, dest string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return err
+func registerSylicity(tracker *progress.Tracker) {
+	tracker.Report(progress.Update{Stage: progress.StageRegister, Fraction: 0, Message: l10n.T("installer.registering")})
+	if err := createDesktopFile(); err != nil {
+		slog.Warn("could not create desktop file", "err", err)
+	} else {
+		slog.Info("registered Sylicity")
 	}
-	defer r.Close()
+	tracker.Report(progress.Update{Stage: progress.StageRegister, Fraction: 1, Message: l10n.T("installer.registered")})
+}
 
-	for _, f := range r.File {
-		fpath := filepath.Join(dest, f.Name)
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, os.ModePerm)
-			continue
-		}
-		if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-			return err
-		}
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return err
-		}
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-		if err != nil {
-			return err
-		}
+func getVersionsDirs(cfg *config.Config) map[string]string {
+	dirs := map[string]string{}
+	for _, channel := range cfg.ChannelNames() {
+		dirs[channel] = cfg.VersionsDir(channel)
 	}
-	return nil
+	return dirs
 }
 
-func runInstallerLogic(opts LaunchOptions, label *widget.Label, cLoader fyne.CanvasObject, track *canvas.Rectangle, chunk *canvas.Rectangle, loaderWidth float32, btn *widget.Button, win fyne.Window) {
-	var wg sync.WaitGroup
-	stopAnimation := make(chan bool, 1)
-	isAnimating := true
-
-	track.Move(fyne.NewPos(0, 0))
-	track.Resize(fyne.NewSize(loaderWidth, 8))
-	track.Refresh()
-
-	wg.Add(1)
-	go animateIndeterminate(chunk, loaderWidth, stopAnimation, &wg)
-
+func newUninstallPlan(cfg *config.Config) (uninstall.Plan, error) {
 	appDir, err := getAppDir()
 	if err != nil {
-		label.SetText(fmt.Sprintf("Error: %v", err))
-		return
+		return uninstall.Plan{}, err
 	}
-
-	label.SetText("Checking for client installation...")
-
-	var progressMu sync.Mutex
-	progressCallback := func(msg string, progress float32) {
-		progressMu.Lock()
-		defer progressMu.Unlock()
-
-		if isAnimating {
-			select {
-			case stopAnimation <- true:
-			default:
-			}
-			wg.Wait()
-			isAnimating = false
-		}
-
-		label.SetText(msg)
-		track.Move(fyne.NewPos(0, 0))
-		track.Resize(fyne.NewSize(loaderWidth, 8))
-		track.Refresh()
-		if progress < 0 {
-			progress = 0
-		}
-		if progress > 1 {
-			progress = 1
-		}
-		chunk.Resize(fyne.NewSize(loaderWidth*progress, 8))
-		chunk.Move(fyne.NewPos(0, 0))
-		chunk.Refresh()
-	}
-
-	forceInstall := opts.LaunchMode != "play"
-
-	if opts.ClientYear != "" {
-		if err := downloadSpecificClient(appDir, opts.ClientYear, progressCallback, forceInstall); err != nil {
-			label.SetText(fmt.Sprintf("Failed to download client: %v", err))
-			return
-		}
-	} else {
-		if err := checkAndUpdateClients(appDir, progressCallback, forceInstall); err != nil {
-			label.SetText(fmt.Sprintf("Failed to update clients: %v", err))
-			return
-		}
+	plan := uninstall.Plan{
+		AppDir:         appDir,
+		ProtocolScheme: protocolScheme,
 	}
-
-	if !isAnimating {
-		isAnimating = true
-		stopAnimation = make(chan bool, 1)
-		track.Move(fyne.NewPos(0, 0))
-		track.Resize(fyne.NewSize(loaderWidth, 8))
-		track.Refresh()
-		wg.Add(1)
-		go animateIndeterminate(chunk, loaderWidth, stopAnimation, &wg)
+	plan.DownloadDir, _ = install.DefaultDownloadDir()
+	for _, dir := range getVersionsDirs(cfg) {
+		plan.VersionsDirs = append(plan.VersionsDirs, dir)
 	}
-
-	if err := createDesktopFile(); err != nil {
-		fmt.Printf("Warning: could not create desktop file: %v\n", err)
-	}
-
-	if opts.LaunchMode == "play" {
-		label.SetText("Starting Sylicity...")
-		if err := launchClient(appDir, opts); err != nil {
-			label.SetText(fmt.Sprintf("Failed to launch: %v", err))
-			if isAnimating {
-				select {
-				case stopAnimation <- true:
-				default:
-				}
-				wg.Wait()
-				isAnimating = false
-			}
-			return
-		}
-	}
-
-	if isAnimating && opts.LaunchMode != "play" {
-		select {
-		case stopAnimation <- true:
-		default:
+	if runtime.GOOS == "linux" {
+		plan.DesktopFile, _ = getDesktopFilePath()
+		if iconThemeDir, err := getIconThemeDir(); err == nil {
+			plan.IconFiles = append(assets.IconPaths(iconThemeDir), assets.IconStamp(iconThemeDir))
 		}
-		wg.Wait()
-		isAnimating = false
-	}
-
-	if opts.LaunchMode != "play" {
-		label.SetText("Sylicity is ready!")
-		cLoader.Hide()
-		btn.SetText("Finish")
-		btn.OnTapped = func() { win.Close() }
-		btn.Refresh()
-	} else {
-		go func() {
-			time.Sleep(5 * time.Second)
-			win.Close()
-		}()
 	}
+	return plan, nil
 }
 
 func getAppDir() (string, error) {
@@ -570,95 +699,191 @@ func getAppDir() (string, error) {
 	return appPath, os.MkdirAll(appPath, 0755)
 }
 
-type ProgressWriter struct {
-	Total      int64
-	Written    int64
-	File       *os.File
-	OnProgress func(float32)
-}
-
-func (pw *ProgressWriter) Write(p []byte) (int, error) {
-	n, err := pw.File.Write(p)
+// setupLogging logs to the app dir at the configured level. Without a config
+// it logs at info level, so the configuration error itself is kept.
+func setupLogging(cfg *config.Config) func() error {
+	level := slog.LevelInfo
+	if cfg != nil {
+		level, _ = logging.ParseLevel(cfg.LogLevel)
+	}
+	appDir, err := getAppDir()
 	if err == nil {
-		pw.Written += int64(n)
-		if pw.Total > 0 {
-			progress := float32(pw.Written) / float32(pw.Total)
-			pw.OnProgress(progress)
+		var closeLog func() error
+		if closeLog, err = logging.Setup(filepath.Join(appDir, "logs"), level); err == nil {
+			return closeLog
 		}
 	}
-	return n, err
+	slog.Warn("could not open log file", "err", err)
+	return func() error { return nil }
 }
 
+func getDesktopFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "share", "applications", "sylicity-installer.desktop"), nil
+}
+
+func getIconThemeDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "share", "icons", "hicolor"), nil
+}
 
 func createDesktopFile() error {
 	if runtime.GOOS != "linux" {
 		return nil
 	}
 
-	homeDir, err := os.UserHomeDir()
+	desktopFilePath, err := getDesktopFilePath()
 	if err != nil {
 		return err
 	}
-	desktopFilePath := filepath.Join(homeDir, ".local", "share", "applications", "sylicity-installer.desktop")
 
 	exePath, err := os.Executable()
 	if err != nil {
 		return err
 	}
-	// TODO: embed the file inside the launcher
-	iconPath := filepath.Join(filepath.Dir(exePath), "Sylicity.png")
+	iconThemeDir, err := getIconThemeDir()
+	if err != nil {
+		return err
+	}
+	if err := assets.InstallIcons(iconThemeDir); err != nil {
+		return err
+	}
 
+	// MimeType makes the entry a handler for sylicity-player: links, which
+	// xdg-open passes in as %u.
 	desktopContent := fmt.Sprintf(`[Desktop Entry]
 Name=Sylicity
 Comment=Sylicity Launcher
-Exec="%s"
+Exec="%s" %%u
 Icon=%s
 Terminal=false
 Type=Application
 Categories=Game;
-`, exePath, iconPath)
+MimeType=x-scheme-handler/%s;
+Actions=Uninstall;
+
+[Desktop Action Uninstall]
+Name=Uninstall Sylicity
+Exec="%s" -uninstall
+`, exePath, assets.IconName, protocolScheme, exePath)
 
 	return os.WriteFile(desktopFilePath, []byte(desktopContent), 0644)
 }
 
-func launchClient(appDir string, opts LaunchOptions) error {
+// superviseWindow is how long a supervised client is watched for a crash.
+const superviseWindow = 30 * time.Second
+
+// launchClient starts the client. With launch.supervise set it also watches
+// it for a while and returns the Exit of a client that crashed on start-up.
+// onWait is called if another process is changing the client first.
+func launchClient(cfg *config.Config, opts LaunchOptions, onWait func()) (*launch.Exit, error) {
 	clientYear := opts.ClientYear
 	if clientYear == "" {
-		clientYear = "2016"
+		clientYear = cfg.DefaultClientYear
 	}
+	slog.Info("starting client", "year", clientYear, "channel", opts.Channel, "mode", opts.LaunchMode)
 
-	clientFolder := fmt.Sprintf("Client%s", clientYear)
-	exeName := "SylicityPlayerBeta.exe"
-	exePath := filepath.Join(appDir, "Versions", clientFolder, exeName)
-
-	if _, err := os.Stat(exePath); os.IsNotExist(err) {
-		return fmt.Errorf("client executable not found: %s", exePath)
+	clientDir := install.ClientDir(cfg.VersionsDir(opts.Channel), clientYear)
+	launchOpts := launch.Options{
+		Executable: filepath.Join(clientDir, install.ExeName),
+		Play:       opts.LaunchMode == "play",
+		AuthURL:    cfg.AuthURL,
+		AuthTicket: opts.AuthTicket,
+		JoinScript: opts.Script,
+		ExtraArgs:  cfg.Launch.ExtraArgs,
+		Wrapper:    cfg.Launch.Wrapper,
+		Env:        cfg.Launch.Env,
 	}
 
-	var args []string
-	if opts.LaunchMode == "play" {
-		args = append(args, "--play")
-		args = append(args, "--authenticationUrl", authURLDefault)
-		args = append(args, "--authenticationTicket", opts.AuthTicket)
-		args = append(args, "--joinScriptUrl", opts.Script)
+	lock, err := install.LockClient(clientDir, filelock.Shared, onWait)
+	if err != nil {
+		return nil, err
 	}
+	// The client keeps the lock while it runs, so an update or uninstall
+	// started meanwhile waits for it to exit.
+	launchOpts.Lock = lock
+	if !cfg.Launch.Supervise {
+		return nil, launch.Client(launch.ExecSpawner{}, launchOpts)
+	}
+
+	launchOpts.LogFile = filepath.Join(install.LogDir(clientDir), fmt.Sprintf("client-%s.log", time.Now().Format("20060102-150405")))
+	return launch.Supervise(launch.ExecSpawner{}, launchOpts, superviseWindow)
+}
 
-	fmt.Printf("Launching client with command:\n%s %s\n", exePath, strings.Join(args, " "))
+func newInstaller(cfg *config.Config, tracker *progress.Tracker) *install.Installer {
+	return &install.Installer{Source: downloadSource(cfg), Tracker: tracker, Concurrency: cfg.DownloadConcurrency}
+}
 
-	cmd := exec.Command(exePath, args...)
-	clientlaunchcalls.SetupProcAttr(cmd)
+var (
+	limiterOnce sync.Once
+	limiter     *download.Limiter
+)
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start client: %w", err)
+// downloadSource applies bandwidthLimit and bandwidthSchedule. Every
+// installer shares one limiter, so the limit covers all downloads of the
+// process together.
+func downloadSource(cfg *config.Config) download.Source {
+	limiterOnce.Do(func() {
+		if cfg.BandwidthLimit == "" && len(cfg.BandwidthSchedule) == 0 {
+			return
+		}
+		rate, err := cfg.DownloadRate()
+		if err != nil {
+			slog.Error("ignoring bandwidth limit", "err", err)
+			return
+		}
+		limiter = download.NewLimiter(rate)
+	})
+	if limiter == nil {
+		return download.Default
 	}
+	return download.Limit(download.Default, limiter)
+}
 
-	if cmd.Process == nil {
-		return fmt.Errorf("process started but process handle is nil")
+func fetchClientVersions(cfg *config.Config, channel string, installer *install.Installer) (map[string]manifest.Client, error) {
+	manifestURL, err := cfg.ManifestURL(channel)
+	if err != nil {
+		return nil, err
 	}
+	clients, err := installer.Manifest(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s client versions: %w", channel, err)
+	}
+	if err := manifest.ResolveURLs(clients, cfg.DownloadURLBase); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s client versions: %w", channel, err)
+	}
+	return clients, nil
+}
 
-	if err := cmd.Process.Release(); err != nil {
-		return fmt.Errorf("started client but failed to detach (release): %w", err)
+// installClients installs the missing clients of channel, or all of them
+// with force set. A non-empty year limits it to that client.
+func installClients(cfg *config.Config, channel, year string, tracker *progress.Tracker, force bool) error {
+	installer := newInstaller(cfg, tracker)
+	clients, err := fetchClientVersions(cfg, channel, installer)
+	if err != nil {
+		return err
+	}
+	if year != "" {
+		info, ok := clients[year]
+		if !ok {
+			return fmt.Errorf("client year %s not available", year)
+		}
+		clients = map[string]manifest.Client{year: info}
 	}
+	return installer.Sync(cfg.VersionsDir(channel), clients, force)
+}
 
-	return nil
+func verifyInstalledClients(cfg *config.Config, channel, year string, repair bool, tracker *progress.Tracker) ([]*install.VerifyResult, error) {
+	installer := newInstaller(cfg, tracker)
+	clients, err := fetchClientVersions(cfg, channel, installer)
+	if err != nil {
+		return nil, err
+	}
+	return installer.VerifyAll(cfg.VersionsDir(channel), clients, year, repair)
 }
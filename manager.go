@@ -0,0 +1,331 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/manifest"
+	"sylicitybootstrapper/progress"
+	"sylicitybootstrapper/uninstall"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+type managedClient struct {
+	Year      string
+	Info      manifest.Client
+	Dir       string
+	Installed *manifest.Installed
+	Present   bool
+	Size      int64
+}
+
+func (c managedClient) updateAvailable() bool {
+	return c.Present && c.Installed != nil && c.Info.Hash != "" && !strings.EqualFold(c.Installed.Hash, c.Info.Hash)
+}
+
+func (c managedClient) status() string {
+	switch {
+	case !c.Present:
+		return l10n.T("manager.notInstalled")
+	case c.Installed == nil:
+		return l10n.T("manager.unknownVersion")
+	case c.updateAvailable():
+		return l10n.T("manager.updateAvailable")
+	default:
+		return l10n.T("manager.upToDate")
+	}
+}
+
+func (c managedClient) installedVersion() string {
+	if c.Installed == nil || c.Installed.Version == "" {
+		return "—"
+	}
+	return c.Installed.Version
+}
+
+func (c managedClient) installedHash() string {
+	if c.Installed == nil || c.Installed.Hash == "" {
+		return "—"
+	}
+	if len(c.Installed.Hash) > 10 {
+		return c.Installed.Hash[:10]
+	}
+	return c.Installed.Hash
+}
+
+func loadManagedClients(cfg *config.Config, channel string) ([]managedClient, error) {
+	clients, err := fetchClientVersions(cfg, channel, newInstaller(cfg, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	versionsDir := cfg.VersionsDir(channel)
+	var managed []managedClient
+	for year, info := range clients {
+		client := managedClient{
+			Year: year,
+			Info: info,
+			Dir:  install.ClientDir(versionsDir, year),
+		}
+		if install.Installed(install.OS, client.Dir) {
+			client.Present = true
+			client.Installed, _ = install.ReadManifest(install.OS, client.Dir)
+			client.Size = dirSize(client.Dir)
+		}
+		managed = append(managed, client)
+	}
+	sort.Slice(managed, func(i, j int) bool { return managed[i].Year < managed[j].Year })
+	return managed, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// showClientManager replaces the window content with a list of every client
+// year in the channel manifest and the actions available for each.
+func showClientManager(cfg *config.Config, channel string, win fyne.Window) {
+	win.SetFixedSize(false)
+	win.Resize(fyne.NewSize(760, 440))
+
+	statusLabel := widget.NewLabel("")
+	progressBar := widget.NewProgressBar()
+	progressBar.Hide()
+	rows := container.NewVBox()
+
+	busy := false
+	var refresh func()
+
+	runAction := func(description string, year string, stages []progress.Stage, action func(installer *install.Installer) error, onDone func()) {
+		if busy {
+			return
+		}
+		busy = true
+		statusLabel.SetText(description)
+		progressBar.SetValue(0)
+		progressBar.Show()
+
+		tracker := progress.NewTracker(stages, func(snapshot progress.Snapshot) {
+			fyne.Do(func() {
+				statusLabel.SetText(snapshot.Message)
+				progressBar.SetValue(snapshot.Overall)
+			})
+		})
+		tracker.SetItems([]string{year})
+
+		go func() {
+			err := action(newInstaller(cfg, tracker))
+			fyne.Do(func() {
+				busy = false
+				progressBar.Hide()
+				if err != nil {
+					slog.Error("client manager action failed", "action", description, "err", err)
+					statusLabel.SetText("")
+					dialog.ShowError(err, win)
+				} else {
+					statusLabel.SetText(l10n.T("manager.done"))
+				}
+				if onDone != nil {
+					onDone()
+				}
+				refresh()
+			})
+		}()
+	}
+
+	buildRow := func(client managedClient) fyne.CanvasObject {
+		size := "—"
+		if client.Present {
+			size = formatBytes(client.Size)
+		}
+		details := container.NewGridWithColumns(5,
+			widget.NewLabelWithStyle(l10n.T("client.name", "Year", client.Year), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			widget.NewLabel(client.installedVersion()),
+			widget.NewLabel(client.installedHash()),
+			widget.NewLabel(size),
+			widget.NewLabel(client.status()),
+		)
+
+		actions := container.NewHBox()
+		if !client.Present {
+			actions.Add(widget.NewButton(l10n.T("manager.install"), func() {
+				runAction(l10n.T("install.installing", "Year", client.Year), client.Year, progress.ClientStages, func(installer *install.Installer) error {
+					return installer.Client(client.Dir, client.Year, client.Info)
+				}, nil)
+			}))
+		} else {
+			if client.updateAvailable() {
+				actions.Add(widget.NewButton(l10n.T("manager.update"), func() {
+					runAction(l10n.T("manager.updating", "Year", client.Year), client.Year, progress.ClientStages, func(installer *install.Installer) error {
+						return installer.Client(client.Dir, client.Year, client.Info)
+					}, nil)
+				}))
+			}
+			actions.Add(widget.NewButton(l10n.T("manager.verify"), func() {
+				var result *install.VerifyResult
+				runAction(l10n.T("install.verifying", "Year", client.Year), client.Year, progress.VerifyStages, func(installer *install.Installer) error {
+					var err error
+					result, err = installer.Verify(client.Dir, client.Year, client.Info)
+					return err
+				}, func() {
+					if result != nil {
+						showVerifyResult(result, win)
+					}
+				})
+			}))
+			repair := func() {
+				runAction(l10n.T("install.repairing", "Year", client.Year), client.Year, progress.ClientStages, func(installer *install.Installer) error {
+					result, err := installer.Verify(client.Dir, client.Year, client.Info)
+					if err != nil {
+						return err
+					}
+					return installer.Repair(client.Dir, client.Year, client.Info, result)
+				}, nil)
+			}
+			actions.Add(widget.NewButton(l10n.T("common.repair"), repair))
+			removeButton := widget.NewButton(l10n.T("manager.remove"), func() {
+				dialog.ShowConfirm(l10n.T("manager.removeTitle"), l10n.T("manager.removeConfirm", "Year", client.Year), func(ok bool) {
+					if !ok {
+						return
+					}
+					runAction(l10n.T("manager.removing", "Year", client.Year), client.Year, nil, func(*install.Installer) error {
+						plan := uninstall.Plan{Clients: []uninstall.Client{{Channel: channel, Year: client.Year, Dir: client.Dir}}}
+						return uninstall.Run(plan).Err()
+					}, nil)
+				}, win)
+			})
+			removeButton.Importance = widget.DangerImportance
+			actions.Add(removeButton)
+			var launchButton *widget.Button
+			launchButton = widget.NewButton(l10n.T("manager.launch"), func() {
+				opts := LaunchOptions{LaunchMode: "manage", ClientYear: client.Year, Channel: channel}
+				launchButton.Disable()
+				go func() {
+					exit, err := launchClient(cfg, opts, func() {
+						fyne.Do(func() { statusLabel.SetText(install.WaitingMessage()) })
+					})
+					fyne.Do(func() {
+						launchButton.Enable()
+						if statusLabel.Text == install.WaitingMessage() {
+							statusLabel.SetText("")
+						}
+						if err != nil {
+							dialog.ShowError(err, win)
+						} else if exit != nil {
+							showCrashDialog(exit, launchButton.OnTapped, repair, win)
+						}
+					})
+				}()
+			})
+			launchButton.Importance = widget.HighImportance
+			actions.Add(launchButton)
+		}
+
+		return container.NewBorder(nil, nil, nil, actions, details)
+	}
+
+	refresh = func() {
+		rows.Objects = []fyne.CanvasObject{widget.NewLabel(l10n.T("manager.loading"))}
+		rows.Refresh()
+		go func() {
+			clients, err := loadManagedClients(cfg, channel)
+			fyne.Do(func() {
+				rows.Objects = nil
+				if err != nil {
+					rows.Add(widget.NewLabel(l10n.T("common.error", "Err", err)))
+					return
+				}
+				for _, client := range clients {
+					rows.Add(buildRow(client))
+					rows.Add(widget.NewSeparator())
+				}
+				rows.Refresh()
+			})
+		}()
+	}
+
+	channelSelect := widget.NewSelect(cfg.ChannelNames(), func(selected string) {
+		if selected != channel && !busy {
+			showClientManager(cfg, selected, win)
+		}
+	})
+	channelSelect.SetSelected(channel)
+
+	header := container.NewBorder(nil, nil,
+		widget.NewLabelWithStyle(l10n.T("manager.title"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.NewHBox(widget.NewLabel(l10n.T("manager.channel")), channelSelect),
+	)
+	columns := container.NewGridWithColumns(5,
+		widget.NewLabel(l10n.T("manager.columnClient")),
+		widget.NewLabel(l10n.T("manager.columnVersion")),
+		widget.NewLabel(l10n.T("manager.columnHash")),
+		widget.NewLabel(l10n.T("manager.columnSize")),
+		widget.NewLabel(l10n.T("manager.columnStatus")),
+	)
+	footer := container.NewBorder(nil, nil, nil,
+		container.NewHBox(
+			widget.NewButton(l10n.T("common.exportDiagnostics"), func() { showExportDiagnostics(cfg, LaunchOptions{Channel: channel}, win) }),
+			widget.NewButton(l10n.T("common.close"), func() { win.Close() }),
+		),
+		container.NewVBox(statusLabel, progressBar),
+	)
+
+	win.SetContent(container.NewBorder(
+		container.NewVBox(header, widget.NewSeparator(), columns),
+		footer,
+		nil, nil,
+		container.NewVScroll(rows),
+	))
+	refresh()
+}
+
+func showVerifyResult(result *install.VerifyResult, win fyne.Window) {
+	lines := []string{result.Summary()}
+	for _, name := range result.Missing {
+		lines = append(lines, l10n.T("verify.missingFile", "Path", name))
+	}
+	for _, name := range result.Modified {
+		lines = append(lines, l10n.T("verify.modifiedFile", "Path", name))
+	}
+	for _, name := range result.Extra {
+		lines = append(lines, l10n.T("verify.extraFile", "Path", name))
+	}
+
+	details := widget.NewLabel(strings.Join(lines, "\n"))
+	scroll := container.NewVScroll(details)
+	scroll.SetMinSize(fyne.NewSize(420, 200))
+	dialog.ShowCustom(l10n.T("client.name", "Year", result.Year), l10n.T("common.close"), scroll, win)
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+)
+
+// InstalledName is the file written next to each client on install so it can
+// be verified later even if the server manifest has no file list.
+const InstalledName = ".sylicity-manifest.json"
+
+type File struct {
+	Path string `json:"path"`
+	SHA1 string `json:"sha1"`
+	Size int64  `json:"size"`
+}
+
+type Client struct {
+	Hash    string `json:"hash"`
+	URL     string `json:"url"`
+	Version string `json:"version,omitempty"`
+
+	// Optional per-file listing. When FilesURL is set, repairs fetch
+	// FilesURL/<path> instead of the whole zip.
+	Files    []File `json:"files,omitempty"`
+	FilesURL string `json:"filesUrl,omitempty"`
+}
+
+// Response is the body of the client versions API.
+type Response struct {
+	Clients map[string]Client `json:"clients"`
+}
+
+type Installed struct {
+	Year    string `json:"year"`
+	Version string `json:"version,omitempty"`
+	Hash    string `json:"hash"`
+	Files   []File `json:"files"`
+}
+
+// Years name the client directories, so anything but four digits could
+// point outside the Versions folder.
+var yearPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+func Decode(r io.Reader) (map[string]Client, error) {
+	var data Response
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	for year := range data.Clients {
+		if !yearPattern.MatchString(year) {
+			return nil, fmt.Errorf("invalid client year %q in manifest", year)
+		}
+	}
+	return data.Clients, nil
+}
+
+// ResolveURLs resolves relative client URLs against base, so a manifest can
+// list paths on the download server. Absolute URLs are kept.
+func ResolveURLs(clients map[string]Client, base string) error {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("invalid download base URL: %w", err)
+	}
+	for year, client := range clients {
+		for _, ref := range []*string{&client.URL, &client.FilesURL} {
+			if *ref == "" {
+				continue
+			}
+			u, err := url.Parse(*ref)
+			if err != nil {
+				return fmt.Errorf("invalid URL for client %s: %w", year, err)
+			}
+			*ref = baseURL.ResolveReference(u).String()
+		}
+		clients[year] = client
+	}
+	return nil
+}
+
+func DecodeInstalled(r io.Reader) (*Installed, error) {
+	var installed Installed
+	if err := json.NewDecoder(r).Decode(&installed); err != nil {
+		return nil, err
+	}
+	return &installed, nil
+}
+
+func EncodeInstalled(w io.Writer, installed Installed) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(installed)
+}
@@ -0,0 +1,22 @@
+package manifest
+
+import "testing"
+
+func TestResolveURLs(t *testing.T) {
+	clients := map[string]Client{
+		"2016": {URL: "clients/2016.zip", FilesURL: "/files/2016"},
+		"2018": {URL: "https://cdn.example.com/2018.zip"},
+	}
+	if err := ResolveURLs(clients, "https://setup.example.com/sylicity/"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Client{
+		"2016": {URL: "https://setup.example.com/sylicity/clients/2016.zip", FilesURL: "https://setup.example.com/files/2016"},
+		"2018": {URL: "https://cdn.example.com/2018.zip"},
+	}
+	for year, client := range want {
+		if got := clients[year]; got.URL != client.URL || got.FilesURL != client.FilesURL {
+			t.Errorf("client %s = %+v, want %+v", year, got, client)
+		}
+	}
+}
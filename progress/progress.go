@@ -0,0 +1,201 @@
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Stage int
+
+const (
+	StageManifest Stage = iota
+	StageDownload
+	StageVerify
+	StageExtract
+	StageRegister
+)
+
+var stageNames = map[Stage]string{
+	StageManifest: "manifest",
+	StageDownload: "download",
+	StageVerify:   "verify",
+	StageExtract:  "extract",
+	StageRegister: "register",
+}
+
+// Relative share of each stage in the overall percentage. Only the stages a
+// tracker is created with count, so the weights are normalised per tracker.
+var stageWeights = map[Stage]float64{
+	StageManifest: 5,
+	StageDownload: 65,
+	StageVerify:   5,
+	StageExtract:  15,
+	StageRegister: 10,
+}
+
+// Item stages run once per client; the others run once per operation.
+func (s Stage) perItem() bool {
+	return s == StageDownload || s == StageVerify || s == StageExtract
+}
+
+func (s Stage) String() string {
+	if name, ok := stageNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("stage(%d)", int(s))
+}
+
+func (s Stage) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+var (
+	// A full install or play run: manifest, every client, then registration.
+	InstallStages = []Stage{StageManifest, StageDownload, StageVerify, StageExtract, StageRegister}
+	// Installing, updating or repairing clients that are already known.
+	ClientStages = []Stage{StageDownload, StageVerify, StageExtract}
+	VerifyStages = []Stage{StageVerify}
+)
+
+// Update is what the installer reports. Fraction is the completed part of
+// Stage for Item, or negative when it cannot be known, e.g. a download
+// without Content-Length.
+type Update struct {
+	Stage    Stage
+	Item     string
+	Fraction float64
+	Message  string
+
+	BytesDone  int64
+	BytesTotal int64
+	Rate       float64
+	ETA        time.Duration
+}
+
+// Snapshot is what front ends render.
+type Snapshot struct {
+	Stage         Stage   `json:"stage"`
+	Item          string  `json:"item,omitempty"`
+	Message       string  `json:"message"`
+	StageProgress float64 `json:"stageProgress"`
+	Overall       float64 `json:"overall"`
+	Indeterminate bool    `json:"indeterminate,omitempty"`
+	BytesDone     int64   `json:"bytesDone,omitempty"`
+	BytesTotal    int64   `json:"bytesTotal,omitempty"`
+	Rate          float64 `json:"bytesPerSecond,omitempty"`
+	ETASeconds    float64 `json:"etaSeconds,omitempty"`
+}
+
+type Tracker struct {
+	// deliver is held from computing a snapshot until onUpdate returns, so
+	// snapshots arrive in the order they were taken. onUpdate must not
+	// report to the same tracker.
+	deliver sync.Mutex
+
+	mu       sync.Mutex
+	stages   []Stage
+	total    float64
+	items    []string
+	itemsSet bool
+	done     map[Stage]map[string]float64
+	onUpdate func(Snapshot)
+
+	// shown is the highest Overall reported so far. A stage reported again,
+	// like the verify after a repair, does not move the bar back.
+	shown float64
+}
+
+func NewTracker(stages []Stage, onUpdate func(Snapshot)) *Tracker {
+	t := &Tracker{
+		stages:   stages,
+		done:     map[Stage]map[string]float64{},
+		onUpdate: onUpdate,
+	}
+	for _, stage := range stages {
+		t.total += stageWeights[stage]
+		t.done[stage] = map[string]float64{}
+	}
+	return t
+}
+
+// SetItems declares the clients the per-item stages are spread across. Until
+// it is called those stages count as not started; with no items they count
+// as complete.
+func (t *Tracker) SetItems(items []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.items = append([]string(nil), items...)
+	t.itemsSet = true
+}
+
+func (t *Tracker) Report(u Update) {
+	if t == nil {
+		return
+	}
+
+	t.deliver.Lock()
+	defer t.deliver.Unlock()
+
+	t.mu.Lock()
+	if stageDone, ok := t.done[u.Stage]; ok && u.Fraction >= 0 {
+		stageDone[u.Item] = min(u.Fraction, 1)
+	}
+	t.shown = max(t.shown, t.overall())
+	snapshot := Snapshot{
+		Stage:         u.Stage,
+		Item:          u.Item,
+		Message:       u.Message,
+		StageProgress: u.Fraction,
+		Overall:       t.shown,
+		Indeterminate: u.Fraction < 0,
+		BytesDone:     u.BytesDone,
+		BytesTotal:    u.BytesTotal,
+		Rate:          u.Rate,
+		ETASeconds:    u.ETA.Seconds(),
+	}
+	t.mu.Unlock()
+
+	if t.onUpdate != nil {
+		t.onUpdate(snapshot)
+	}
+}
+
+// Message reports a status line without moving any stage forward.
+func (t *Tracker) Message(stage Stage, item, message string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	fraction := t.done[stage][item]
+	t.mu.Unlock()
+	t.Report(Update{Stage: stage, Item: item, Fraction: fraction, Message: message})
+}
+
+func (t *Tracker) overall() float64 {
+	if t.total == 0 {
+		return 0
+	}
+	var sum float64
+	for _, stage := range t.stages {
+		weight := stageWeights[stage]
+		stageDone := t.done[stage]
+		switch {
+		case !stage.perItem():
+			sum += weight * stageDone[""]
+		case !t.itemsSet:
+		case len(t.items) == 0:
+			sum += weight
+		default:
+			var itemSum float64
+			for _, item := range t.items {
+				itemSum += stageDone[item]
+			}
+			sum += weight * itemSum / float64(len(t.items))
+		}
+	}
+	return sum / t.total
+}
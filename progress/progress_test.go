@@ -0,0 +1,177 @@
+package progress
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	snapshots []Snapshot
+}
+
+func (r *recorder) add(s Snapshot) {
+	r.snapshots = append(r.snapshots, s)
+}
+
+func (r *recorder) last() Snapshot {
+	return r.snapshots[len(r.snapshots)-1]
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestStageWeights(t *testing.T) {
+	rec := &recorder{}
+	tracker := NewTracker(InstallStages, rec.add)
+	tracker.SetItems([]string{"2016"})
+
+	// Weights add up to 100 for InstallStages, so each one is its share.
+	steps := []struct {
+		update Update
+		want   float64
+	}{
+		{Update{Stage: StageManifest, Fraction: 1}, 0.05},
+		{Update{Stage: StageDownload, Item: "2016", Fraction: 0.5}, 0.05 + 0.325},
+		{Update{Stage: StageDownload, Item: "2016", Fraction: 1}, 0.70},
+		{Update{Stage: StageVerify, Item: "2016", Fraction: 1}, 0.75},
+		{Update{Stage: StageExtract, Item: "2016", Fraction: 1}, 0.90},
+		{Update{Stage: StageRegister, Fraction: 1}, 1},
+	}
+	for _, step := range steps {
+		tracker.Report(step.update)
+		if got := rec.last().Overall; !near(got, step.want) {
+			t.Errorf("after %s %.2f: overall = %v, want %v", step.update.Stage, step.update.Fraction, got, step.want)
+		}
+	}
+}
+
+func TestWeightsAreNormalisedPerTracker(t *testing.T) {
+	rec := &recorder{}
+	tracker := NewTracker(VerifyStages, rec.add)
+	tracker.SetItems([]string{"2016"})
+	tracker.Report(Update{Stage: StageVerify, Item: "2016", Fraction: 0.5})
+	if got := rec.last().Overall; !near(got, 0.5) {
+		t.Errorf("verify only: overall = %v, want 0.5", got)
+	}
+
+	// Stages the tracker was not created with do not move it.
+	tracker.Report(Update{Stage: StageDownload, Item: "2016", Fraction: 1})
+	if got := rec.last().Overall; !near(got, 0.5) {
+		t.Errorf("unknown stage moved overall to %v", got)
+	}
+}
+
+func TestPerItemAveraging(t *testing.T) {
+	rec := &recorder{}
+	tracker := NewTracker(ClientStages, rec.add)
+
+	tracker.Report(Update{Stage: StageDownload, Item: "2016", Fraction: 1})
+	if got := rec.last().Overall; got != 0 {
+		t.Errorf("before SetItems: overall = %v, want 0", got)
+	}
+
+	tracker.SetItems([]string{"2016", "2018", "2020", "2021"})
+	tracker.Report(Update{Stage: StageDownload, Item: "2018", Fraction: 0.5})
+	// Download is 65 of 85: one and a half of four clients done.
+	if got, want := rec.last().Overall, 65.0/85*1.5/4; !near(got, want) {
+		t.Errorf("overall = %v, want %v", got, want)
+	}
+
+	tracker.Report(Update{Stage: StageDownload, Item: "2018", Fraction: 7})
+	if got, want := rec.last().Overall, 65.0/85*2/4; !near(got, want) {
+		t.Errorf("fraction above 1: overall = %v, want %v", got, want)
+	}
+
+	tracker.SetItems(nil)
+	tracker.Report(Update{Stage: StageVerify})
+	if got := rec.last().Overall; !near(got, 1) {
+		t.Errorf("with no items: overall = %v, want 1", got)
+	}
+}
+
+func TestOverallNeverGoesBackwards(t *testing.T) {
+	rec := &recorder{}
+	tracker := NewTracker(ClientStages, rec.add)
+	tracker.SetItems([]string{"2016"})
+
+	tracker.Report(Update{Stage: StageVerify, Item: "2016", Fraction: 1})
+	tracker.Report(Update{Stage: StageExtract, Item: "2016", Fraction: 1})
+	before := rec.last().Overall
+	// A repair verifies the client again from the start.
+	tracker.Report(Update{Stage: StageVerify, Item: "2016", Fraction: 0})
+	if got := rec.last(); got.Overall != before || got.StageProgress != 0 {
+		t.Errorf("re-verify: overall = %v, stage = %v; want %v, 0", got.Overall, got.StageProgress, before)
+	}
+	tracker.Report(Update{Stage: StageVerify, Item: "2016", Fraction: 1})
+	if got := rec.last().Overall; got != before {
+		t.Errorf("after re-verify: overall = %v, want %v", got, before)
+	}
+}
+
+func TestIndeterminate(t *testing.T) {
+	rec := &recorder{}
+	tracker := NewTracker(ClientStages, rec.add)
+	tracker.SetItems([]string{"2016"})
+
+	tracker.Report(Update{Stage: StageDownload, Item: "2016", Fraction: 0.4})
+	tracker.Report(Update{Stage: StageDownload, Item: "2016", Fraction: -1, Message: "waiting"})
+	got := rec.last()
+	if !got.Indeterminate || got.Message != "waiting" {
+		t.Errorf("snapshot = %+v, want an indeterminate one", got)
+	}
+	if want := 65.0 / 85 * 0.4; !near(got.Overall, want) {
+		t.Errorf("indeterminate update moved overall to %v, want %v", got.Overall, want)
+	}
+
+	tracker.Message(StageDownload, "2016", "still waiting")
+	if got := rec.last(); got.Indeterminate || !near(got.StageProgress, 0.4) || got.Message != "still waiting" {
+		t.Errorf("Message snapshot = %+v, want the last known fraction", got)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.SetItems([]string{"2016"})
+	tracker.Report(Update{Stage: StageDownload, Fraction: 1})
+	tracker.Message(StageDownload, "", "ignored")
+}
+
+func TestSnapshotsArriveInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var overall []float64
+	first := make(chan struct{})
+	release := make(chan struct{})
+	tracker := NewTracker(VerifyStages, func(s Snapshot) {
+		if s.Item == "2016" {
+			close(first)
+			<-release
+		}
+		mu.Lock()
+		overall = append(overall, s.Overall)
+		mu.Unlock()
+	})
+	tracker.SetItems([]string{"2016", "2018"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		tracker.Report(Update{Stage: StageVerify, Item: "2016", Fraction: 1})
+	}()
+	<-first
+	// The second report is taken while the first is still being delivered.
+	go func() {
+		defer wg.Done()
+		tracker.Report(Update{Stage: StageVerify, Item: "2018", Fraction: 1})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if len(overall) != 2 || overall[0] != 0.5 || overall[1] != 1 {
+		t.Errorf("overall arrived as %v, want [0.5 1]", overall)
+	}
+}
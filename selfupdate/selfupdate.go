@@ -0,0 +1,271 @@
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"sylicitybootstrapper/download"
+)
+
+// Set on the relaunched process so it does not immediately check for updates
+// again. It holds the PID of the process that relaunched it.
+const RelaunchedEnv = "SYLICITY_SELFUPDATED"
+
+// publicKeyBase64 checks update signatures. Release builds set it with
+// -ldflags "-X sylicitybootstrapper/selfupdate.publicKeyBase64=<key>".
+var publicKeyBase64 = ""
+
+// Enabled reports whether this build has a key to check updates with.
+func Enabled() bool {
+	return publicKeyBase64 != ""
+}
+
+// checkClient fetches the version document, which is small, so a server
+// that stops answering must not hold up the launch.
+var checkClient = &http.Client{Timeout: 15 * time.Second}
+
+type Release struct {
+	Version  string            `json:"version"`
+	Binaries map[string]Binary `json:"binaries"`
+}
+
+// Signature is a base64 ed25519 signature over the raw SHA-256 digest of the binary.
+type Binary struct {
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Check returns the binary to install when versionURL advertises a newer
+// version than current, or nil when the running build is up to date.
+func Check(versionURL, current string) (*Release, *Binary, error) {
+	resp, err := checkClient.Get(versionURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("version server returned status: %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, nil, fmt.Errorf("invalid version response: %w", err)
+	}
+
+	if CompareVersions(release.Version, current) <= 0 {
+		return &release, nil, nil
+	}
+
+	bin, ok := release.Binaries[Platform()]
+	if !ok {
+		return &release, nil, fmt.Errorf("version %s has no binary for %s", release.Version, Platform())
+	}
+	return &release, &bin, nil
+}
+
+// CompareVersions compares versions such as "1.4.2", "v1.5" or
+// "1.5.0-beta.2" the way semver orders them: a pre-release comes before its
+// release, and build metadata after "+" is ignored.
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	if c := compareIdentifiers(aCore, bCore, true); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == nil && bPre == nil:
+		return 0
+	case aPre == nil:
+		return 1
+	case bPre == nil:
+		return -1
+	}
+	return compareIdentifiers(aPre, bPre, false)
+}
+
+// splitVersion returns the dotted release and pre-release parts of v. The
+// pre-release is nil when there is none.
+func splitVersion(v string) (core, pre []string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, preRelease, hasPre := strings.Cut(v, "-")
+	core = strings.Split(v, ".")
+	if hasPre {
+		pre = strings.Split(preRelease, ".")
+	}
+	return core, pre
+}
+
+// compareIdentifiers compares dot-separated parts one by one. Numeric parts
+// compare as numbers and before any text part. When one list runs out first,
+// missing release parts count as 0, while a shorter pre-release is older.
+func compareIdentifiers(as, bs []string, padZero bool) int {
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		if !padZero && (i >= len(as) || i >= len(bs)) {
+			if len(as) < len(bs) {
+				return -1
+			}
+			return 1
+		}
+		var ap, bp string
+		if i < len(as) {
+			ap = as[i]
+		}
+		if i < len(bs) {
+			bp = bs[i]
+		}
+		if ap == "" {
+			ap = "0"
+		}
+		if bp == "" {
+			bp = "0"
+		}
+		an, aErr := strconv.Atoi(ap)
+		bn, bErr := strconv.Atoi(bp)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(ap, bp); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// Apply downloads bin from src next to the running executable, verifies its
+// hash and signature and swaps it in place of the running executable.
+func Apply(src download.Source, bin Binary) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	newPath, err := fetch(src, bin, filepath.Dir(exePath))
+	if err != nil {
+		return err
+	}
+
+	if err := replaceExecutable(exePath, newPath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	return nil
+}
+
+func fetch(src download.Source, bin Binary, dir string) (string, error) {
+	wantHash, err := hex.DecodeString(bin.SHA256)
+	if err != nil || len(wantHash) != sha256.Size {
+		return "", fmt.Errorf("invalid sha256 in release: %q", bin.SHA256)
+	}
+	signature, err := base64.StdEncoding.DecodeString(bin.Signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("invalid update public key")
+	}
+
+	out, err := os.CreateTemp(dir, ".sylicity-update-*")
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	err = download.Fetch(src, bin.URL, io.MultiWriter(out, hash), nil)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+
+	digest := hash.Sum(nil)
+	if hex.EncodeToString(digest) != strings.ToLower(bin.SHA256) {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("hash mismatch: expected %s, got %x", bin.SHA256, digest)
+	}
+	if !ed25519.Verify(publicKey, digest, signature) {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("signature verification failed")
+	}
+
+	if err := os.Chmod(out.Name(), 0755); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+func oldPath(exePath string) string {
+	return exePath + ".old"
+}
+
+// WaitForPrevious waits for the bootstrapper that relaunched this one to exit,
+// so its single-instance lock and log file are free again. It returns at once
+// when this process was not started by an update.
+func WaitForPrevious() {
+	pid, err := strconv.Atoi(os.Getenv(RelaunchedEnv))
+	if err != nil || pid == os.Getpid() {
+		return
+	}
+	waitForExit(pid, 10*time.Second)
+}
+
+// CleanupOld removes the executable left behind by a previous update.
+func CleanupOld() {
+	exePath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	os.Remove(oldPath(exePath))
+}
+
+// Relaunch starts the updated executable with args and does not return on success.
+func Relaunch(args []string) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+	env := append(os.Environ(), RelaunchedEnv+"="+strconv.Itoa(os.Getpid()))
+	return relaunch(exePath, args, env)
+}
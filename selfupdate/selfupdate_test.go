@@ -0,0 +1,160 @@
+package selfupdate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	// Each version is older than the next.
+	ordered := []string{
+		"0.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.3-beta",
+		"v1.2.3",
+		"1.2.10",
+		"1.10",
+		"2",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := CompareVersions(ordered[i], ordered[j]); got != want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	equal := [][2]string{
+		{"1.5", "1.5.0"},
+		{"v1.5", "1.5"},
+		{" 1.5.0 ", "1.5"},
+		{"1.5.0+build.7", "1.5.0"},
+		{"1.5.0-beta+exp", "1.5.0-beta"},
+	}
+	for _, pair := range equal {
+		if got := CompareVersions(pair[0], pair[1]); got != 0 {
+			t.Errorf("CompareVersions(%q, %q) = %d, want 0", pair[0], pair[1], got)
+		}
+	}
+}
+
+type memSource map[string][]byte
+
+func (s memSource) Open(url string) (io.ReadCloser, int64, error) {
+	data, ok := s[url]
+	if !ok {
+		return nil, 0, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+// truncatedSource announces the full size but sends only part of the body.
+type truncatedSource []byte
+
+func (s truncatedSource) Open(url string) (io.ReadCloser, int64, error) {
+	return io.NopCloser(bytes.NewReader(s[:len(s)/2])), int64(len(s)), nil
+}
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func sign(key ed25519.PrivateKey, url string, data []byte) Binary {
+	digest := sha256.Sum256(data)
+	return Binary{
+		URL:       url,
+		SHA256:    hex.EncodeToString(digest[:]),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest[:])),
+	}
+}
+
+func TestFetchVerifiesSignature(t *testing.T) {
+	key := newKey(t)
+	old := publicKeyBase64
+	publicKeyBase64 = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	defer func() { publicKeyBase64 = old }()
+
+	const url = "https://example.com/sylicity"
+	data := []byte("#!/bin/sh\necho new bootstrapper\n")
+	src := memSource{url: data}
+	good := sign(key, url, data)
+
+	badSignature := good
+	badSignature.Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+	wrongKey := sign(newKey(t), url, data)
+	otherBinary := sign(key, url, []byte("something else"))
+
+	tests := []struct {
+		name string
+		src  memSource
+		bin  Binary
+		want string
+	}{
+		{"bad signature", src, badSignature, "signature verification failed"},
+		{"wrong key", src, wrongKey, "signature verification failed"},
+		{"hash of another binary", src, otherBinary, "hash mismatch"},
+		{"bad sha256", src, Binary{URL: url, SHA256: "abc", Signature: good.Signature}, "invalid sha256"},
+		{"bad signature encoding", src, Binary{URL: url, SHA256: good.SHA256, Signature: "!!"}, "invalid signature encoding"},
+		{"missing binary", memSource{}, good, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path, err := fetch(tt.src, tt.bin, dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("fetch = %q, %v; want an error containing %q", path, err, tt.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("left %d files behind", len(entries))
+			}
+		})
+	}
+
+	t.Run("truncated binary", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := fetch(truncatedSource(data), good, dir); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("fetch = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("left %d files behind", len(entries))
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		path, err := fetch(src, good, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("downloaded %q, %v; want %q", got, err, data)
+		}
+	})
+}
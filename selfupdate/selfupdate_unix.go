@@ -0,0 +1,20 @@
+//go:build unix
+
+package selfupdate
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func replaceExecutable(exePath, newPath string) error {
+	return os.Rename(newPath, exePath)
+}
+
+func relaunch(exePath string, args, env []string) error {
+	return syscall.Exec(exePath, append([]string{exePath}, args...), env)
+}
+
+// syscall.Exec keeps the process, so there is never an older one to wait for.
+func waitForExit(pid int, timeout time.Duration) {}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// A running executable cannot be overwritten on Windows, but it can be
+// renamed. The old binary is moved aside and removed by CleanupOld on the
+// next start.
+func replaceExecutable(exePath, newPath string) error {
+	old := oldPath(exePath)
+	os.Remove(old)
+	if err := os.Rename(exePath, old); err != nil {
+		return err
+	}
+	if err := os.Rename(newPath, exePath); err != nil {
+		if restoreErr := os.Rename(old, exePath); restoreErr != nil {
+			return fmt.Errorf("%w (restoring previous executable also failed: %v)", err, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// The new process is started before this one exits, so it waits for this one
+// with WaitForPrevious.
+func relaunch(exePath string, args, env []string) error {
+	cmd := exec.Command(exePath, args...)
+	cmd.Env = env
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	cmd.Process.Release()
+	os.Exit(0)
+	return nil
+}
+
+func waitForExit(pid int, timeout time.Duration) {
+	process, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		// It is already gone.
+		return
+	}
+	defer windows.CloseHandle(process)
+	windows.WaitForSingleObject(process, uint32(timeout.Milliseconds()))
+}
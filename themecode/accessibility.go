@@ -0,0 +1,9 @@
+package themecode
+
+// Accessibility holds the user's accessibility preferences.
+type Accessibility struct {
+	// HighContrast asks for a high-contrast palette.
+	HighContrast bool
+	// ReducedMotion asks for as little animation as possible.
+	ReducedMotion bool
+}
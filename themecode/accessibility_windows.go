@@ -0,0 +1,45 @@
+//go:build windows
+
+package themecode
+
+import (
+	"log/slog"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	spiGetHighContrast        = 0x0042
+	spiGetClientAreaAnimation = 0x1042
+	hcfHighContrastOn         = 0x1
+)
+
+var systemParametersInfo = windows.NewLazySystemDLL("user32.dll").NewProc("SystemParametersInfoW")
+
+type highContrast struct {
+	size          uint32
+	flags         uint32
+	defaultScheme *uint16
+}
+
+// DetectAccessibility reads the High Contrast setting and whether "Show
+// animations in Windows" is turned off.
+func DetectAccessibility() Accessibility {
+	var access Accessibility
+
+	contrast := highContrast{size: uint32(unsafe.Sizeof(highContrast{}))}
+	if ok, _, err := systemParametersInfo.Call(spiGetHighContrast, uintptr(contrast.size), uintptr(unsafe.Pointer(&contrast)), 0); ok != 0 {
+		access.HighContrast = contrast.flags&hcfHighContrastOn != 0
+	} else {
+		slog.Debug("could not read the High Contrast setting", "err", err)
+	}
+
+	var animations int32
+	if ok, _, err := systemParametersInfo.Call(spiGetClientAreaAnimation, 0, uintptr(unsafe.Pointer(&animations)), 0); ok != 0 {
+		access.ReducedMotion = animations == 0
+	} else {
+		slog.Debug("could not read the client area animation setting", "err", err)
+	}
+	return access
+}
@@ -0,0 +1,418 @@
+//go:build unix && !darwin
+
+package themecode
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalPath      = "/org/freedesktop/portal/desktop"
+	portalSettings  = "org.freedesktop.portal.Settings"
+	appearanceScope = "org.freedesktop.appearance"
+)
+
+// errNoPreference means a source works but the user has not picked a variant.
+var errNoPreference = errors.New("no preference set")
+
+// DetectSystemTheme asks, in order:
+//
+//  1. the settings portal's org.freedesktop.appearance color-scheme, which
+//     also works inside Flatpak and on desktops such as XFCE, Cinnamon,
+//     MATE, Hyprland and Sway that run xdg-desktop-portal;
+//  2. kdeglobals, on KDE Plasma;
+//  3. gsettings' org.gnome.desktop.interface color-scheme.
+//
+// The first one with an answer wins; without any, the theme is dark.
+func DetectSystemTheme() fyne.ThemeVariant {
+	env := systemEnv()
+	if portal, ok := env.portal.(*portalReader); ok {
+		defer portal.conn.Close()
+	}
+	return detectFrom(themeSources(env))
+}
+
+// settingsReader reads one value from the settings portal.
+type settingsReader interface {
+	Read(namespace, key string) (any, error)
+}
+
+// freedesktopEnv is what the detectors look at, so tests can fake it.
+type freedesktopEnv struct {
+	// portal is nil when there is no session bus.
+	portal    settingsReader
+	desktop   string
+	configDir string
+	gsettings func(schema, key string) (string, error)
+}
+
+func systemEnv() freedesktopEnv {
+	env := freedesktopEnv{
+		desktop:   strings.ToLower(os.Getenv("XDG_CURRENT_DESKTOP")),
+		gsettings: runGsettings,
+	}
+	if conn, err := dbus.ConnectSessionBus(); err == nil {
+		env.portal = &portalReader{conn: conn}
+	} else {
+		slog.Debug("could not connect to the session bus", "err", err)
+	}
+	env.configDir, _ = os.UserConfigDir()
+	return env
+}
+
+// DetectAccentColor asks the settings portal's accent-color, then the KDE
+// AccentColor in kdeglobals, then GNOME's accent-color, in the same way as
+// DetectSystemTheme. ok is false when none of them has one.
+func DetectAccentColor() (accent color.Color, ok bool) {
+	env := systemEnv()
+	if portal, ok := env.portal.(*portalReader); ok {
+		defer portal.conn.Close()
+	}
+	return firstAnswer("accent color", accentSources(env))
+}
+
+// DetectAccessibility asks the settings portal's contrast and then GNOME's
+// high-contrast for HighContrast, and the KDE AnimationDurationFactor and
+// then GNOME's enable-animations for ReducedMotion.
+func DetectAccessibility() Accessibility {
+	env := systemEnv()
+	if portal, ok := env.portal.(*portalReader); ok {
+		defer portal.conn.Close()
+	}
+	highContrast, _ := firstAnswer("high contrast", highContrastSources(env))
+	reducedMotion, _ := firstAnswer("reduced motion", reducedMotionSources(env))
+	return Accessibility{HighContrast: highContrast, ReducedMotion: reducedMotion}
+}
+
+func (env freedesktopEnv) isKDE() bool {
+	return strings.Contains(env.desktop, "kde") || strings.Contains(env.desktop, "plasma")
+}
+
+func (env freedesktopEnv) kdeglobals() (string, error) {
+	if env.configDir == "" {
+		return "", errors.New("no config directory")
+	}
+	return filepath.Join(env.configDir, "kdeglobals"), nil
+}
+
+// source is one way of finding a setting. It returns an error when it has
+// no answer, and the next source is tried.
+type source[T any] struct {
+	name   string
+	detect func() (T, error)
+}
+
+func firstAnswer[T any](setting string, sources []source[T]) (T, bool) {
+	for _, source := range sources {
+		value, err := source.detect()
+		if err == nil {
+			slog.Debug("detected system setting", "setting", setting, "source", source.name, "value", value)
+			return value, true
+		}
+		slog.Debug("system setting source has no answer", "setting", setting, "source", source.name, "err", err)
+	}
+	var zero T
+	return zero, false
+}
+
+func themeSources(env freedesktopEnv) []source[fyne.ThemeVariant] {
+	sources := []source[fyne.ThemeVariant]{
+		{"portal", func() (fyne.ThemeVariant, error) { return portalColorScheme(env.portal) }},
+	}
+	if env.isKDE() {
+		sources = append(sources, source[fyne.ThemeVariant]{"kdeglobals", func() (fyne.ThemeVariant, error) {
+			path, err := env.kdeglobals()
+			if err != nil {
+				return 0, err
+			}
+			return detectKDETheme(path)
+		}})
+	}
+	return append(sources, source[fyne.ThemeVariant]{"gsettings", func() (fyne.ThemeVariant, error) {
+		return detectGnomeTheme(env.gsettings)
+	}})
+}
+
+func detectFrom(sources []source[fyne.ThemeVariant]) fyne.ThemeVariant {
+	if variant, ok := firstAnswer("theme", sources); ok {
+		return variant
+	}
+	slog.Info("could not detect the system theme, using dark theme")
+	return theme.VariantDark
+}
+
+func accentSources(env freedesktopEnv) []source[color.Color] {
+	sources := []source[color.Color]{
+		{"portal", func() (color.Color, error) { return portalAccentColor(env.portal) }},
+	}
+	if env.isKDE() {
+		sources = append(sources, source[color.Color]{"kdeglobals", func() (color.Color, error) {
+			path, err := env.kdeglobals()
+			if err != nil {
+				return nil, err
+			}
+			return kdeAccentColor(path)
+		}})
+	}
+	return append(sources, source[color.Color]{"gsettings", func() (color.Color, error) {
+		return gnomeAccentColor(env.gsettings)
+	}})
+}
+
+// portalColorScheme maps color-scheme: 1 prefers dark, 2 prefers light and
+// 0 has no preference.
+func portalColorScheme(portal settingsReader) (fyne.ThemeVariant, error) {
+	if portal == nil {
+		return 0, errors.New("no session bus")
+	}
+	value, err := portal.Read(appearanceScope, "color-scheme")
+	if err != nil {
+		return 0, err
+	}
+	scheme, ok := value.(uint32)
+	if !ok {
+		return 0, fmt.Errorf("unexpected color-scheme value %v", value)
+	}
+	switch scheme {
+	case 1:
+		return theme.VariantDark, nil
+	case 2:
+		return theme.VariantLight, nil
+	}
+	return 0, errNoPreference
+}
+
+func highContrastSources(env freedesktopEnv) []source[bool] {
+	return []source[bool]{
+		{"portal", func() (bool, error) { return portalContrast(env.portal) }},
+		{"gsettings", func() (bool, error) {
+			return gsettingsBool(env.gsettings, "org.gnome.desktop.a11y.interface", "high-contrast")
+		}},
+	}
+}
+
+func reducedMotionSources(env freedesktopEnv) []source[bool] {
+	var sources []source[bool]
+	if env.isKDE() {
+		sources = append(sources, source[bool]{"kdeglobals", func() (bool, error) {
+			path, err := env.kdeglobals()
+			if err != nil {
+				return false, err
+			}
+			value, err := kdeValue(path, "KDE", "AnimationDurationFactor")
+			if err != nil {
+				return false, err
+			}
+			factor, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("unexpected AnimationDurationFactor %q", value)
+			}
+			return factor == 0, nil
+		}})
+	}
+	return append(sources, source[bool]{"gsettings", func() (bool, error) {
+		animations, err := gsettingsBool(env.gsettings, "org.gnome.desktop.interface", "enable-animations")
+		return !animations, err
+	}})
+}
+
+// portalContrast maps contrast: 1 asks for higher contrast and 0 has no
+// preference.
+func portalContrast(portal settingsReader) (bool, error) {
+	if portal == nil {
+		return false, errors.New("no session bus")
+	}
+	value, err := portal.Read(appearanceScope, "contrast")
+	if err != nil {
+		return false, err
+	}
+	contrast, ok := value.(uint32)
+	if !ok {
+		return false, fmt.Errorf("unexpected contrast value %v", value)
+	}
+	if contrast == 1 {
+		return true, nil
+	}
+	return false, errNoPreference
+}
+
+func gsettingsBool(gsettings func(schema, key string) (string, error), schema, key string) (bool, error) {
+	value, err := gsettings(schema, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("unexpected %s %s value %q", schema, key, value)
+	}
+	return b, nil
+}
+
+// portalAccentColor reads accent-color, three doubles from 0 to 1. Values
+// outside that range mean no accent is set.
+func portalAccentColor(portal settingsReader) (color.Color, error) {
+	if portal == nil {
+		return nil, errors.New("no session bus")
+	}
+	value, err := portal.Read(appearanceScope, "accent-color")
+	if err != nil {
+		return nil, err
+	}
+	rgb, ok := value.([]any)
+	if !ok || len(rgb) != 3 {
+		return nil, fmt.Errorf("unexpected accent-color value %v", value)
+	}
+	var channels [3]uint8
+	for i, v := range rgb {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected accent-color value %v", value)
+		}
+		if f < 0 || f > 1 {
+			return nil, errNoPreference
+		}
+		channels[i] = uint8(f*255 + 0.5)
+	}
+	return color.NRGBA{R: channels[0], G: channels[1], B: channels[2], A: 0xff}, nil
+}
+
+// kdeAccentColor reads AccentColor=r,g,b from the [General] section.
+func kdeAccentColor(configFile string) (color.Color, error) {
+	value, err := kdeValue(configFile, "General", "AccentColor")
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("unexpected AccentColor %q", value)
+	}
+	var channels [3]uint8
+	for i := range channels {
+		n, err := strconv.ParseUint(strings.TrimSpace(parts[i]), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected AccentColor %q", value)
+		}
+		channels[i] = uint8(n)
+	}
+	return color.NRGBA{R: channels[0], G: channels[1], B: channels[2], A: 0xff}, nil
+}
+
+// kdeValue reads key from [section] of a KDE config file.
+func kdeValue(configFile, section, key string) (string, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filepath.Base(configFile), err)
+	}
+	inSection := false
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inSection = line == "["+section+"]"
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if ok && inSection && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", fmt.Errorf("no %s in [%s] of %s", key, section, filepath.Base(configFile))
+}
+
+// gnomeAccents are the colors behind GNOME's named accent-color values.
+var gnomeAccents = map[string]color.NRGBA{
+	"blue":   {R: 0x35, G: 0x84, B: 0xe4, A: 0xff},
+	"teal":   {R: 0x21, G: 0x90, B: 0xa4, A: 0xff},
+	"green":  {R: 0x3a, G: 0x94, B: 0x4a, A: 0xff},
+	"yellow": {R: 0xc8, G: 0x88, B: 0x00, A: 0xff},
+	"orange": {R: 0xed, G: 0x5b, B: 0x00, A: 0xff},
+	"red":    {R: 0xe6, G: 0x2d, B: 0x42, A: 0xff},
+	"pink":   {R: 0xd5, G: 0x61, B: 0x99, A: 0xff},
+	"purple": {R: 0x91, G: 0x41, B: 0xac, A: 0xff},
+	"slate":  {R: 0x6f, G: 0x83, B: 0x96, A: 0xff},
+}
+
+func gnomeAccentColor(gsettings func(schema, key string) (string, error)) (color.Color, error) {
+	name, err := gsettings("org.gnome.desktop.interface", "accent-color")
+	if err != nil {
+		return nil, err
+	}
+	accent, ok := gnomeAccents[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown accent-color %q", name)
+	}
+	return accent, nil
+}
+
+type portalReader struct {
+	conn *dbus.Conn
+}
+
+func (p *portalReader) Read(namespace, key string) (any, error) {
+	var value dbus.Variant
+	err := p.conn.Object("org.freedesktop.portal.Desktop", portalPath).Call(portalSettings+".Read", 0, namespace, key).Store(&value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s from the settings portal: %w", namespace, key, err)
+	}
+	// Read wraps the value in a second variant.
+	result := value.Value()
+	if inner, ok := result.(dbus.Variant); ok {
+		result = inner.Value()
+	}
+	return result, nil
+}
+
+// detectKDETheme treats any ColorScheme with "dark" in its name, such as
+// BreezeDark, as dark.
+func detectKDETheme(configFile string) (fyne.ThemeVariant, error) {
+	scheme, err := kdeValue(configFile, "General", "ColorScheme")
+	if err != nil {
+		return 0, err
+	}
+	if strings.Contains(strings.ToLower(scheme), "dark") {
+		return theme.VariantDark, nil
+	}
+	return theme.VariantLight, nil
+}
+
+// detectGnomeTheme reads color-scheme, which is prefer-dark, prefer-light or
+// default. GNOME shows default as light.
+func detectGnomeTheme(gsettings func(schema, key string) (string, error)) (fyne.ThemeVariant, error) {
+	scheme, err := gsettings("org.gnome.desktop.interface", "color-scheme")
+	if err != nil {
+		return 0, err
+	}
+	if scheme == "prefer-dark" {
+		return theme.VariantDark, nil
+	}
+	return theme.VariantLight, nil
+}
+
+func runGsettings(schema, key string) (string, error) {
+	output, err := exec.Command("gsettings", "get", schema, key).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run gsettings: %w", err)
+	}
+	return parseGsettingsValue(string(output)), nil
+}
+
+// parseGsettingsValue turns gsettings output such as 'prefer-dark' or true
+// into the bare value.
+func parseGsettingsValue(output string) string {
+	value := strings.TrimSpace(output)
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,311 @@
+//go:build unix && !darwin
+
+package themecode
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// fakePortal answers Read like the settings portal over D-Bus.
+type fakePortal map[string]any
+
+func (p fakePortal) Read(namespace, key string) (any, error) {
+	value, ok := p[namespace+" "+key]
+	if !ok {
+		return nil, errors.New("org.freedesktop.portal.Error.NotFound")
+	}
+	return value, nil
+}
+
+func fakeGsettings(value string, err error) func(string, string) (string, error) {
+	return func(schema, key string) (string, error) {
+		if schema != "org.gnome.desktop.interface" || key != "color-scheme" {
+			return "", errors.New("unexpected key " + schema + " " + key)
+		}
+		return value, err
+	}
+}
+
+// newTestEnv is a freedesktop environment with kdeglobals, if not empty,
+// written to a temporary config directory.
+func newTestEnv(t *testing.T, portal settingsReader, desktop, kdeglobals string, gsettings func(string, string) (string, error)) freedesktopEnv {
+	t.Helper()
+	configDir := t.TempDir()
+	if kdeglobals != "" {
+		if err := os.WriteFile(filepath.Join(configDir, "kdeglobals"), []byte(kdeglobals), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return freedesktopEnv{portal: portal, desktop: desktop, configDir: configDir, gsettings: gsettings}
+}
+
+func TestDetectFrom(t *testing.T) {
+	noGsettings := fakeGsettings("", errors.New("gsettings not found"))
+	tests := []struct {
+		name      string
+		portal    settingsReader
+		desktop   string
+		kdeglobal string
+		gsettings func(string, string) (string, error)
+		want      fyne.ThemeVariant
+	}{
+		{
+			name:      "portal wins over gsettings",
+			portal:    fakePortal{"org.freedesktop.appearance color-scheme": uint32(1)},
+			gsettings: fakeGsettings("default", nil),
+			want:      theme.VariantDark,
+		},
+		{
+			name:      "portal light",
+			portal:    fakePortal{"org.freedesktop.appearance color-scheme": uint32(2)},
+			gsettings: noGsettings,
+			want:      theme.VariantLight,
+		},
+		{
+			name:      "no preference falls through to kdeglobals",
+			portal:    fakePortal{"org.freedesktop.appearance color-scheme": uint32(0)},
+			desktop:   "kde",
+			kdeglobal: "[General]\nColorScheme=BreezeLight\n",
+			gsettings: noGsettings,
+			want:      theme.VariantLight,
+		},
+		{
+			name:      "kdeglobals ignored outside KDE",
+			desktop:   "xfce",
+			kdeglobal: "[General]\nColorScheme=BreezeLight\n",
+			gsettings: fakeGsettings("prefer-dark", nil),
+			want:      theme.VariantDark,
+		},
+		{
+			name:      "portal without appearance falls through to gsettings",
+			portal:    fakePortal{},
+			desktop:   "gnome",
+			gsettings: fakeGsettings("default", nil),
+			want:      theme.VariantLight,
+		},
+		{
+			name:      "unexpected portal value",
+			portal:    fakePortal{"org.freedesktop.appearance color-scheme": "dark"},
+			desktop:   "sway",
+			gsettings: fakeGsettings("default", nil),
+			want:      theme.VariantLight,
+		},
+		{
+			name:      "kdeglobals without ColorScheme falls through",
+			desktop:   "kde",
+			kdeglobal: "[General]\nfont=Noto Sans\n[Colors:View]\nColorScheme=Dark\n",
+			gsettings: fakeGsettings("default", nil),
+			want:      theme.VariantLight,
+		},
+		{
+			name:      "nothing answers",
+			desktop:   "kde",
+			gsettings: noGsettings,
+			want:      theme.VariantDark,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.portal, tt.desktop, tt.kdeglobal, tt.gsettings)
+			if got := detectFrom(themeSources(env)); got != tt.want {
+				t.Errorf("got variant %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccentSources(t *testing.T) {
+	accentGsettings := func(value string) func(string, string) (string, error) {
+		return func(schema, key string) (string, error) {
+			if key != "accent-color" {
+				return "", errors.New("unexpected key " + key)
+			}
+			if value == "" {
+				return "", errors.New("no such key")
+			}
+			return value, nil
+		}
+	}
+	tests := []struct {
+		name      string
+		portal    settingsReader
+		desktop   string
+		kdeglobal string
+		gsettings string
+		want      color.Color
+	}{
+		{
+			name:      "portal",
+			portal:    fakePortal{"org.freedesktop.appearance accent-color": []any{0.2, 0.4, 1.0}},
+			gsettings: "teal",
+			want:      color.NRGBA{R: 0x33, G: 0x66, B: 0xff, A: 0xff},
+		},
+		{
+			name:      "unset portal accent falls through to kdeglobals",
+			portal:    fakePortal{"org.freedesktop.appearance accent-color": []any{-1.0, -1.0, -1.0}},
+			desktop:   "KDE",
+			kdeglobal: "[General]\nAccentColor=61,174,233\nColorScheme=BreezeDark\n",
+			want:      color.NRGBA{R: 61, G: 174, B: 233, A: 0xff},
+		},
+		{
+			name:      "AccentColor outside [General] is ignored",
+			desktop:   "plasma",
+			kdeglobal: "[WM]\nAccentColor=1,2,3\n",
+			gsettings: "purple",
+			want:      gnomeAccents["purple"],
+		},
+		{
+			name:      "gsettings",
+			desktop:   "gnome",
+			gsettings: "orange",
+			want:      gnomeAccents["orange"],
+		},
+		{
+			name:    "nothing answers",
+			desktop: "gnome",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.portal, strings.ToLower(tt.desktop), tt.kdeglobal, accentGsettings(tt.gsettings))
+			got, ok := firstAnswer("accent color", accentSources(env))
+			if ok != (tt.want != nil) || got != tt.want {
+				t.Errorf("got %v, %v; want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessibilitySources(t *testing.T) {
+	settings := func(values map[string]string) func(string, string) (string, error) {
+		return func(schema, key string) (string, error) {
+			value, ok := values[schema+" "+key]
+			if !ok {
+				return "", errors.New("no such key")
+			}
+			return value, nil
+		}
+	}
+	tests := []struct {
+		name      string
+		portal    settingsReader
+		desktop   string
+		kdeglobal string
+		gsettings map[string]string
+		want      Accessibility
+	}{
+		{
+			name:   "portal asks for high contrast",
+			portal: fakePortal{"org.freedesktop.appearance contrast": uint32(1)},
+			gsettings: map[string]string{
+				"org.gnome.desktop.a11y.interface high-contrast": "false",
+			},
+			want: Accessibility{HighContrast: true},
+		},
+		{
+			name:   "gsettings",
+			portal: fakePortal{"org.freedesktop.appearance contrast": uint32(0)},
+			gsettings: map[string]string{
+				"org.gnome.desktop.a11y.interface high-contrast": "true",
+				"org.gnome.desktop.interface enable-animations":  "false",
+			},
+			want: Accessibility{HighContrast: true, ReducedMotion: true},
+		},
+		{
+			name:      "KDE animations off",
+			desktop:   "kde",
+			kdeglobal: "[KDE]\nAnimationDurationFactor=0\n",
+			gsettings: map[string]string{"org.gnome.desktop.interface enable-animations": "true"},
+			want:      Accessibility{ReducedMotion: true},
+		},
+		{
+			name:      "KDE slowed animations",
+			desktop:   "kde",
+			kdeglobal: "[KDE]\nAnimationDurationFactor=0.5\n",
+			want:      Accessibility{},
+		},
+		{
+			name: "nothing answers",
+			want: Accessibility{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.portal, tt.desktop, tt.kdeglobal, settings(tt.gsettings))
+			highContrast, _ := firstAnswer("high contrast", highContrastSources(env))
+			reducedMotion, _ := firstAnswer("reduced motion", reducedMotionSources(env))
+			if got := (Accessibility{HighContrast: highContrast, ReducedMotion: reducedMotion}); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectKDEThemeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    fyne.ThemeVariant
+		wantErr bool
+	}{
+		{fixture: "breeze-dark", want: theme.VariantDark},
+		{fixture: "breeze-light", want: theme.VariantLight},
+		{fixture: "general-last", want: theme.VariantDark},
+		{fixture: "no-scheme", wantErr: true},
+		{fixture: "empty", wantErr: true},
+		{fixture: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := detectKDETheme(filepath.Join("testdata", "kdeglobals", tt.fixture))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("got variant %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	accent, err := kdeAccentColor(filepath.Join("testdata", "kdeglobals", "breeze-dark"))
+	if want := (color.NRGBA{R: 61, G: 174, B: 233, A: 0xff}); err != nil || accent != want {
+		t.Errorf("AccentColor = %v, %v; want %v", accent, err, want)
+	}
+}
+
+func TestGsettingsFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		value   string
+		want    fyne.ThemeVariant
+	}{
+		{"prefer-dark", "prefer-dark", theme.VariantDark},
+		{"prefer-light", "prefer-light", theme.VariantLight},
+		{"default", "default", theme.VariantLight},
+		{"bool", "true", theme.VariantLight},
+		{"double-quoted", "it's quoted", theme.VariantLight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			output, err := os.ReadFile(filepath.Join("testdata", "gsettings", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			value := parseGsettingsValue(string(output))
+			if value != tt.value {
+				t.Errorf("parsed %q, want %q", value, tt.value)
+			}
+			got, err := detectGnomeTheme(func(string, string) (string, error) { return value, nil })
+			if err != nil || got != tt.want {
+				t.Errorf("got variant %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
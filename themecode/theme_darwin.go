@@ -0,0 +1,69 @@
+//go:build darwin
+
+package themecode
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// errNotSet means defaults has no value for the key.
+var errNotSet = errors.New("not set")
+
+// DetectSystemTheme reads AppleInterfaceStyle, which is "Dark" in dark mode
+// and missing in light mode.
+func DetectSystemTheme() fyne.ThemeVariant {
+	style, err := readDefault("-g", "AppleInterfaceStyle")
+	switch {
+	case errors.Is(err, errNotSet):
+		return theme.VariantLight
+	case err != nil:
+		slog.Warn("could not read AppleInterfaceStyle, using dark theme", "err", err)
+		return theme.VariantDark
+	case strings.EqualFold(style, "Dark"):
+		return theme.VariantDark
+	default:
+		return theme.VariantLight
+	}
+}
+
+// DetectAccessibility reads "Increase contrast" and "Reduce motion" from the
+// Accessibility settings.
+func DetectAccessibility() Accessibility {
+	var access Accessibility
+	if value, err := readDefault("com.apple.universalaccess", "increaseContrast"); err == nil {
+		access.HighContrast = value == "1"
+	}
+	if value, err := readDefault("com.apple.universalaccess", "reduceMotion"); err == nil {
+		access.ReducedMotion = value == "1"
+	}
+	return access
+}
+
+func DetectAccentColor() (color.Color, bool) {
+	return nil, false
+}
+
+func watchSystem(func()) (func(), error) {
+	return nil, errors.ErrUnsupported
+}
+
+func readDefault(domain, key string) (string, error) {
+	output, err := exec.Command("defaults", "read", domain, key).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// defaults fails with "does not exist" for keys that were never set.
+		return "", errNotSet
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to run defaults: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
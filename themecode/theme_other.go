@@ -1,15 +1,29 @@
-//go:build !linux && !freebsd && !windows
+//go:build !unix && !windows
 
 package themecode
 
 import (
-	"log"
-	
+	"errors"
+	"image/color"
+	"log/slog"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/theme"
 )
 
 func DetectSystemTheme() fyne.ThemeVariant {
-	log.Println("No theme for this OS")
+	slog.Debug("no system theme detection for this OS, using dark theme")
 	return theme.VariantDark
 }
+
+func watchSystem(func()) (func(), error) {
+	return nil, errors.ErrUnsupported
+}
+
+func DetectAccentColor() (color.Color, bool) {
+	return nil, false
+}
+
+func DetectAccessibility() Accessibility {
+	return Accessibility{}
+}
@@ -3,23 +3,26 @@
 package themecode
 
 import (
-	"log"
+	"image/color"
+	"log/slog"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/theme"
 	"golang.org/x/sys/windows/registry"
 )
 
+const personalizeKey = `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
+
 func DetectSystemTheme() fyne.ThemeVariant {
-	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`, registry.QUERY_VALUE)
+	key, err := registry.OpenKey(registry.CURRENT_USER, personalizeKey, registry.QUERY_VALUE)
 	if err != nil {
-		log.Println("Could not open registry key, falling back to dark theme:", err)
+		slog.Warn("could not open the Personalize registry key, using dark theme", "err", err)
 		return theme.VariantDark
 	}
 	defer key.Close()
 	lightThemeVal, _, err := key.GetIntegerValue("AppsUseLightTheme")
 	if err != nil {
-		log.Println("Could not read registry value, falling back to dark theme:", err)
+		slog.Warn("could not read AppsUseLightTheme, using dark theme", "err", err)
 		return theme.VariantDark
 	}
 	if lightThemeVal == 1 {
@@ -27,3 +30,19 @@ func DetectSystemTheme() fyne.ThemeVariant {
 	}
 	return theme.VariantDark
 }
+
+// DetectAccentColor reads the DWM AccentColor, stored as 0xAABBGGRR.
+func DetectAccentColor() (color.Color, bool) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\DWM`, registry.QUERY_VALUE)
+	if err != nil {
+		slog.Debug("could not open the DWM registry key", "err", err)
+		return nil, false
+	}
+	defer key.Close()
+	value, _, err := key.GetIntegerValue("AccentColor")
+	if err != nil {
+		slog.Debug("could not read AccentColor", "err", err)
+		return nil, false
+	}
+	return color.NRGBA{R: uint8(value), G: uint8(value >> 8), B: uint8(value >> 16), A: 0xff}, true
+}
@@ -0,0 +1,23 @@
+package themecode
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// Watch calls onChange with the new variant each time the system switches
+// between light and dark mode, until stop is called. onChange runs on a
+// background goroutine.
+func Watch(onChange func(fyne.ThemeVariant)) (stop func(), err error) {
+	var mu sync.Mutex
+	current := DetectSystemTheme()
+	return watchSystem(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if variant := DetectSystemTheme(); variant != current {
+			current = variant
+			onChange(variant)
+		}
+	})
+}
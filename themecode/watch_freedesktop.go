@@ -0,0 +1,98 @@
+//go:build unix && !darwin
+
+package themecode
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/godbus/dbus/v5"
+)
+
+// watchSystem listens for the settings portal's SettingChanged signal, and
+// watches kdeglobals when no portal is running.
+func watchSystem(notify func()) (stop func(), err error) {
+	stop, err = watchPortal(notify)
+	if err == nil {
+		return stop, nil
+	}
+	slog.Debug("settings portal unavailable, watching kdeglobals", "err", err)
+	return watchKDEGlobals(notify)
+}
+
+func watchPortal(notify func()) (func(), error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the session bus: %w", err)
+	}
+	// Read fails when no portal implements Settings, in which case no
+	// signal would ever arrive.
+	if _, err := (&portalReader{conn: conn}).Read(appearanceScope, "color-scheme"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(portalPath),
+		dbus.WithMatchInterface(portalSettings),
+		dbus.WithMatchMember("SettingChanged"),
+		dbus.WithMatchArg(0, appearanceScope),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to SettingChanged: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+	go func() {
+		// The channel is closed with the connection.
+		for signal := range signals {
+			if len(signal.Body) >= 2 && signal.Body[1] == "color-scheme" {
+				notify()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { conn.Close() }) }, nil
+}
+
+func watchKDEGlobals(notify func()) (func(), error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	// KDE replaces kdeglobals rather than writing it in place, so watch the
+	// directory it lives in.
+	if err := watcher.Add(configDir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", configDir, err)
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) == "kdeglobals" && event.Has(fsnotify.Write|fsnotify.Create) {
+					notify()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("kdeglobals watcher failed", "err", err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { watcher.Close() }) }, nil
+}
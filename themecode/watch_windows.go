@@ -0,0 +1,55 @@
+//go:build windows
+
+package themecode
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// watchSystem waits for registry change notifications on the Personalize
+// key, which holds AppsUseLightTheme.
+func watchSystem(notify func()) (stop func(), err error) {
+	key, err := registry.OpenKey(registry.CURRENT_USER, personalizeKey, registry.NOTIFY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the Personalize registry key: %w", err)
+	}
+	changed, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		key.Close()
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+	done, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		key.Close()
+		windows.CloseHandle(changed)
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	go func() {
+		// A notification is dropped when the thread that asked for it exits.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer key.Close()
+		defer windows.CloseHandle(changed)
+		defer windows.CloseHandle(done)
+		for {
+			if err := windows.RegNotifyChangeKeyValue(windows.Handle(key), false, windows.REG_NOTIFY_CHANGE_LAST_SET, changed, true); err != nil {
+				slog.Warn("could not watch the Personalize registry key", "err", err)
+				return
+			}
+			event, err := windows.WaitForMultipleObjects([]windows.Handle{changed, done}, false, windows.INFINITE)
+			if err != nil || event != windows.WAIT_OBJECT_0 {
+				return
+			}
+			notify()
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { windows.SetEvent(done) }) }, nil
+}
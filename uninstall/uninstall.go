@@ -0,0 +1,207 @@
+package uninstall
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+	"sylicitybootstrapper/l10n"
+)
+
+type Client struct {
+	Channel string
+	Year    string
+	Dir     string
+}
+
+func (c Client) String() string {
+	return l10n.T("client.nameWithChannel", "Year", c.Year, "Channel", c.Channel)
+}
+
+type Plan struct {
+	// Clients to remove. When RemoveAll is set every installed client and
+	// the Versions folders are removed regardless of this list, except for
+	// clients another process is using.
+	Clients   []Client
+	RemoveAll bool
+
+	// KeepData keeps settings and logs in AppDir. It only applies with
+	// RemoveAll.
+	KeepData bool
+
+	AppDir       string
+	VersionsDirs []string
+	// DownloadDir is the bootstrapper's own folder for client downloads.
+	DownloadDir    string
+	DesktopFile    string
+	IconFiles      []string
+	ProtocolScheme string
+}
+
+type Report struct {
+	Removed []string
+	Errors  []error
+}
+
+func (r *Report) remove(path, description string) {
+	if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err := os.RemoveAll(path); err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("failed to remove %s: %w", description, err))
+		return
+	}
+	r.Removed = append(r.Removed, description)
+}
+
+// lockClient takes the lock of the client in dir for removal. It returns nil
+// and reports the client when another process is installing, verifying or
+// launching it.
+func (r *Report) lockClient(dir, description string) *filelock.Lock {
+	lock, err := filelock.TryAcquire(install.LockPath(dir), filelock.Exclusive)
+	if errors.Is(err, filelock.ErrLocked) {
+		r.Errors = append(r.Errors, fmt.Errorf("failed to remove %s: it is in use by another Sylicity process", description))
+		return nil
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Errorf("failed to remove %s: %w", description, err))
+		return nil
+	}
+	return lock
+}
+
+// removeClient removes a client unless another process is using it.
+func (r *Report) removeClient(client Client) {
+	lock := r.lockClient(client.Dir, client.String())
+	if lock == nil {
+		return
+	}
+	// The lock file stays: a process already waiting on it would otherwise
+	// lock a file nobody else can see.
+	r.remove(client.Dir, client.String())
+	lock.Release()
+}
+
+// removeExcept removes dir, but keeps the clients in keep along with their
+// lock files and the folders they sit in.
+func (r *Report) removeExcept(dir string, keep []string) {
+	kept := func(path string) bool {
+		for _, clientDir := range keep {
+			if path == clientDir || path == install.LockPath(clientDir) || strings.HasPrefix(clientDir, path+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	if !kept(dir) {
+		r.remove(dir, dir)
+		return
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if path := filepath.Join(dir, entry.Name()); !kept(path) {
+			r.remove(path, path)
+		}
+	}
+}
+
+func (r *Report) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// FindClients lists the ClientYYYY folders in each versions dir, keyed by channel.
+func FindClients(versionsDirs map[string]string) []Client {
+	var clients []Client
+	for channel, dir := range versionsDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			year, ok := strings.CutPrefix(entry.Name(), "Client")
+			if !ok || !entry.IsDir() {
+				continue
+			}
+			clients = append(clients, Client{
+				Channel: channel,
+				Year:    year,
+				Dir:     filepath.Join(dir, entry.Name()),
+			})
+		}
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].Channel != clients[j].Channel {
+			return clients[i].Channel < clients[j].Channel
+		}
+		return clients[i].Year < clients[j].Year
+	})
+	return clients
+}
+
+func Run(plan Plan) *Report {
+	report := &Report{}
+
+	if !plan.RemoveAll {
+		for _, client := range plan.Clients {
+			report.removeClient(client)
+		}
+		return report
+	}
+
+	// Channel folders live inside the stable Versions folder, so remove the
+	// deepest paths first to report each of them.
+	dirs := append([]string(nil), plan.VersionsDirs...)
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+
+	// Clients are removed under their locks first, so nothing is deleted
+	// from under a running install or launch. The lock files go with the
+	// Versions folders once released.
+	var inUse []string
+	for _, dir := range dirs {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "Client") {
+				continue
+			}
+			clientDir := filepath.Join(dir, entry.Name())
+			lock := report.lockClient(clientDir, clientDir)
+			if lock == nil {
+				inUse = append(inUse, clientDir)
+				continue
+			}
+			report.remove(clientDir, clientDir)
+			lock.Release()
+		}
+	}
+	for _, dir := range dirs {
+		report.removeExcept(dir, inUse)
+	}
+
+	if plan.DownloadDir != "" {
+		report.remove(plan.DownloadDir, plan.DownloadDir)
+	}
+	if plan.DesktopFile != "" {
+		report.remove(plan.DesktopFile, plan.DesktopFile)
+	}
+	for _, path := range plan.IconFiles {
+		report.remove(path, path)
+	}
+	if plan.ProtocolScheme != "" {
+		removed, err := unregisterURLHandler(plan.ProtocolScheme)
+		report.Removed = append(report.Removed, removed...)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("failed to unregister %s URL handler: %w", plan.ProtocolScheme, err))
+		}
+	}
+
+	if !plan.KeepData && plan.AppDir != "" {
+		report.remove(plan.AppDir, "settings and logs in "+plan.AppDir)
+	}
+
+	return report
+}
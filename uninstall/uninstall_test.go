@@ -0,0 +1,183 @@
+package uninstall
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"sylicitybootstrapper/filelock"
+	"sylicitybootstrapper/install"
+)
+
+func mkdir(t *testing.T, path string) string {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func touch(t *testing.T, path string) string {
+	t.Helper()
+	mkdir(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// newTree lays out an installation with a stable and a beta channel, with
+// the beta Versions folder inside the stable one.
+func newTree(t *testing.T) (Plan, map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	versionsDirs := map[string]string{
+		"stable": filepath.Join(root, "Versions"),
+		"beta":   filepath.Join(root, "Versions", "beta"),
+	}
+	for _, dir := range versionsDirs {
+		touch(t, filepath.Join(dir, "Client2016", install.ExeName))
+	}
+	mkdir(t, filepath.Join(versionsDirs["stable"], "Client2018"))
+	touch(t, filepath.Join(versionsDirs["stable"], "notes.txt"))
+
+	plan := Plan{
+		AppDir:       mkdir(t, filepath.Join(root, "app")),
+		VersionsDirs: []string{versionsDirs["stable"], versionsDirs["beta"]},
+		DownloadDir:  filepath.Dir(touch(t, filepath.Join(root, "cache", "Sylicity", "downloads", "client-1.zip"))),
+		DesktopFile:  touch(t, filepath.Join(root, "sylicity.desktop")),
+		IconFiles:    []string{touch(t, filepath.Join(root, "icons", "64.png")), filepath.Join(root, "icons", "missing.png")},
+	}
+	touch(t, filepath.Join(plan.AppDir, "config.json"))
+	return plan, versionsDirs
+}
+
+func TestFindClients(t *testing.T) {
+	_, versionsDirs := newTree(t)
+	var got []string
+	for _, client := range FindClients(versionsDirs) {
+		got = append(got, client.Channel+"/"+client.Year)
+	}
+	if want := []string{"beta/2016", "stable/2016", "stable/2018"}; !slices.Equal(got, want) {
+		t.Errorf("FindClients = %v, want %v", got, want)
+	}
+}
+
+func TestRunRemovesSelectedClients(t *testing.T) {
+	plan, versionsDirs := newTree(t)
+	clients := FindClients(versionsDirs)
+	plan.Clients = clients[1:2]
+	plan.KeepData = false
+
+	report := Run(plan)
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 1 {
+		t.Errorf("removed %v, want only the stable 2016 client", report.Removed)
+	}
+	if exists(clients[1].Dir) {
+		t.Error("selected client is still there")
+	}
+	if !exists(install.LockPath(clients[1].Dir)) {
+		t.Error("lock file was removed with the client")
+	}
+	for _, path := range []string{clients[0].Dir, clients[2].Dir, plan.DownloadDir, plan.DesktopFile, plan.AppDir} {
+		if !exists(path) {
+			t.Errorf("%s was removed without remove all", path)
+		}
+	}
+}
+
+func TestRunSkipsClientsInUse(t *testing.T) {
+	plan, versionsDirs := newTree(t)
+	client := FindClients(versionsDirs)[0]
+	plan.Clients = []Client{client}
+
+	lock, err := install.LockClient(client.Dir, filelock.Shared, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	report := Run(plan)
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "in use by another Sylicity process") {
+		t.Errorf("Run error = %v, want an in use error", err)
+	}
+	if len(report.Removed) != 0 || !exists(client.Dir) {
+		t.Errorf("client in use was removed: %v", report.Removed)
+	}
+}
+
+func TestRunRemoveAll(t *testing.T) {
+	for _, keepData := range []bool{true, false} {
+		plan, _ := newTree(t)
+		plan.RemoveAll = true
+		plan.KeepData = keepData
+		outside := touch(t, filepath.Join(filepath.Dir(plan.DownloadDir), "client-other.zip"))
+
+		report := Run(plan)
+		if err := report.Err(); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range append(plan.VersionsDirs, plan.DownloadDir, plan.DesktopFile, plan.IconFiles[0]) {
+			if exists(path) {
+				t.Errorf("keepData=%v: %s is still there", keepData, path)
+			}
+		}
+		if !exists(outside) {
+			t.Errorf("keepData=%v: removed %s outside the download folder", keepData, outside)
+		}
+		if exists(plan.AppDir) != keepData {
+			t.Errorf("keepData=%v: app dir exists = %v", keepData, exists(plan.AppDir))
+		}
+		// Each client is reported, then the beta folder before the stable
+		// one it sits in. Missing files are not reported at all.
+		want := 8
+		if !keepData {
+			want++
+		}
+		if len(report.Removed) != want {
+			t.Errorf("keepData=%v: removed %v, want %d items", keepData, report.Removed, want)
+		}
+		if beta, stable := slices.Index(report.Removed, plan.VersionsDirs[1]), slices.Index(report.Removed, plan.VersionsDirs[0]); beta < 0 || beta > stable {
+			t.Errorf("removed %v, want the beta folder before the stable one", report.Removed)
+		}
+	}
+}
+
+func TestRunRemoveAllSkipsClientsInUse(t *testing.T) {
+	plan, versionsDirs := newTree(t)
+	plan.RemoveAll = true
+	plan.KeepData = true
+	clients := FindClients(versionsDirs)
+	inUse, other := clients[0], clients[1]
+
+	lock, err := install.LockClient(inUse.Dir, filelock.Shared, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	report := Run(plan)
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "in use by another Sylicity process") {
+		t.Errorf("Run error = %v, want an in use error", err)
+	}
+	for _, path := range []string{inUse.Dir, install.LockPath(inUse.Dir)} {
+		if !exists(path) {
+			t.Errorf("%s of the client in use was removed", path)
+		}
+	}
+	for _, path := range []string{other.Dir, filepath.Join(versionsDirs["stable"], "notes.txt"), plan.DesktopFile} {
+		if exists(path) {
+			t.Errorf("%s is still there", path)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package uninstall
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The .desktop file itself is removed by Run; this drops the scheme
+// association from mimeapps.list so xdg-open stops pointing at it.
+func unregisterURLHandler(scheme string) ([]string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	mimeapps := filepath.Join(configDir, "mimeapps.list")
+
+	data, err := os.ReadFile(mimeapps)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := "x-scheme-handler/" + scheme + "="
+	var kept []string
+	changed := false
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), mimeType) {
+			changed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	if err := os.WriteFile(mimeapps, []byte(strings.Join(kept, "\n")+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return []string{scheme + ": handler in " + mimeapps}, nil
+}
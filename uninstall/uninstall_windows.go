@@ -0,0 +1,40 @@
+//go:build windows
+
+package uninstall
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func unregisterURLHandler(scheme string) ([]string, error) {
+	path := `Software\Classes\` + scheme
+	if err := deleteKeyTree(registry.CURRENT_USER, path); err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []string{`HKEY_CURRENT_USER\` + path}, nil
+}
+
+// registry.DeleteKey refuses keys that still have subkeys, and the handler
+// lives under shell\open\command.
+func deleteKeyTree(root registry.Key, path string) error {
+	key, err := registry.OpenKey(root, path, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return err
+	}
+	subkeys, err := key.ReadSubKeyNames(-1)
+	key.Close()
+	if err != nil {
+		return err
+	}
+	for _, subkey := range subkeys {
+		if err := deleteKeyTree(root, path+`\`+subkey); err != nil {
+			return err
+		}
+	}
+	return registry.DeleteKey(root, path)
+}
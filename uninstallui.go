@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/uninstall"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+func showUninstallView(cfg *config.Config, win fyne.Window) {
+	plan, err := newUninstallPlan(cfg)
+	if err != nil {
+		win.SetContent(widget.NewLabel(l10n.T("common.error", "Err", err)))
+		return
+	}
+
+	clients := uninstall.FindClients(getVersionsDirs(cfg))
+	names := make([]string, len(clients))
+	for i, client := range clients {
+		names[i] = client.String()
+	}
+
+	clientChecks := widget.NewCheckGroup(names, nil)
+	clientChecks.SetSelected(names)
+	clientChecks.Disable()
+
+	// Settings and logs are only removed with everything else, so keeping
+	// them is only a choice then.
+	keepDataCheck := widget.NewCheck(l10n.T("uninstall.keepData"), nil)
+	keepDataCheck.SetChecked(true)
+
+	removeAllCheck := widget.NewCheck(l10n.T("uninstall.removeEverything"), func(checked bool) {
+		if checked {
+			clientChecks.SetSelected(names)
+			clientChecks.Disable()
+			keepDataCheck.Enable()
+		} else {
+			clientChecks.Enable()
+			keepDataCheck.Disable()
+		}
+	})
+	removeAllCheck.SetChecked(true)
+
+	title := widget.NewLabelWithStyle(l10n.T("uninstall.title"), fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	var uninstallButton *widget.Button
+	uninstallButton = widget.NewButton(l10n.T("uninstall.button"), func() {
+		plan.RemoveAll = removeAllCheck.Checked
+		plan.KeepData = keepDataCheck.Checked
+		if !plan.RemoveAll {
+			selected := map[string]bool{}
+			for _, name := range clientChecks.Selected {
+				selected[name] = true
+			}
+			for _, client := range clients {
+				if selected[client.String()] {
+					plan.Clients = append(plan.Clients, client)
+				}
+			}
+		}
+
+		uninstallButton.Disable()
+		go func() {
+			report := uninstall.Run(plan)
+			slog.Info("uninstall finished", "removed", report.Removed, "err", report.Err())
+			fyne.Do(func() { showUninstallReport(report, win) })
+		}()
+	})
+	uninstallButton.Importance = widget.DangerImportance
+	cancelButton := widget.NewButton(l10n.T("common.cancel"), func() { win.Close() })
+
+	clientList := container.NewVScroll(clientChecks)
+	clientList.SetMinSize(fyne.NewSize(0, 80))
+	if len(clients) == 0 {
+		clientList.Content = widget.NewLabel(l10n.T("uninstall.noClients"))
+	}
+
+	win.SetContent(container.NewBorder(
+		title,
+		container.NewVBox(removeAllCheck, keepDataCheck, container.NewCenter(container.NewHBox(cancelButton, uninstallButton))),
+		nil, nil,
+		clientList,
+	))
+}
+
+func showUninstallReport(report *uninstall.Report, win fyne.Window) {
+	var lines []string
+	if len(report.Removed) == 0 {
+		lines = append(lines, l10n.T("uninstall.nothingRemoved"))
+	}
+	for _, item := range report.Removed {
+		lines = append(lines, l10n.T("uninstall.removed", "Item", item))
+	}
+	for _, err := range report.Errors {
+		lines = append(lines, l10n.T("common.error", "Err", err))
+	}
+
+	summary := widget.NewLabel(strings.Join(lines, "\n"))
+	summary.Wrapping = fyne.TextWrapWord
+
+	title := l10n.T("uninstall.done")
+	if report.Err() != nil {
+		title = l10n.T("uninstall.doneWithErrors")
+	}
+
+	win.SetContent(container.NewBorder(
+		widget.NewLabelWithStyle(title, fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		container.NewCenter(widget.NewButton(l10n.T("common.close"), func() { win.Close() })),
+		nil, nil,
+		container.NewVScroll(summary),
+	))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"sylicitybootstrapper/config"
+	"sylicitybootstrapper/l10n"
+	"sylicitybootstrapper/progress"
+)
+
+func runVerifyLogic(cfg *config.Config, opts LaunchOptions, events chan<- installerEvent) {
+	defer close(events)
+	repair := opts.LaunchMode == "repair"
+
+	stages := progress.VerifyStages
+	if repair {
+		stages = progress.ClientStages
+	}
+	tracker := progress.NewTracker(stages, func(snapshot progress.Snapshot) {
+		events <- installerEvent{Kind: eventProgress, Progress: snapshot}
+	})
+
+	results, err := verifyInstalledClients(cfg, opts.Channel, opts.ClientYear, repair, tracker)
+	if err != nil {
+		slog.Error("verifying clients failed", "repair", repair, "err", err)
+	}
+
+	var lines []string
+	broken := false
+	for _, result := range results {
+		lines = append(lines, result.Summary())
+		if !result.OK() {
+			broken = true
+		}
+	}
+	switch {
+	case err != nil:
+		lines = append(lines, l10n.T("common.error", "Err", err))
+	case len(results) == 0:
+		lines = append(lines, l10n.T("verify.nothingInstalled"))
+	}
+
+	events <- installerEvent{
+		Kind:       eventDone,
+		Message:    strings.Join(lines, "\n"),
+		Err:        err,
+		Repairable: broken && !repair && err == nil,
+	}
+}